@@ -5,8 +5,13 @@ go 1.23
 require (
 	github.com/evanw/esbuild v0.24.2
 	github.com/gorilla/websocket v1.5.3
+	github.com/mattn/go-sqlite3 v1.14.24
 	github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	rogchap.com/v8go v0.9.0
 )
 
-require golang.org/x/sys v0.28.0 // indirect
+require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+)
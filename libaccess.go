@@ -0,0 +1,127 @@
+package frizzante
+
+import (
+	"bufio"
+	"errors"
+	uuid "github.com/nu7hatch/gouuid"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+type AccessEntry struct {
+	Time      string  `json:"time"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	ClientIp  string  `json:"client_ip"`
+	Scheme    string  `json:"scheme"`
+	Host      string  `json:"host"`
+	Status    int     `json:"status"`
+	Bytes     int64   `json:"bytes"`
+	Duration  float64 `json:"duration_ms"`
+	SessionId string  `json:"session_id,omitempty"`
+	RequestId string  `json:"request_id"`
+	TraceId   string  `json:"trace_id,omitempty"`
+}
+
+// accessWriter wraps a response writer in order to keep track of the status code and of the bytes written.
+type accessWriter struct {
+	writer     http.ResponseWriter
+	statusCode int
+	bytes      int64
+}
+
+func (self *accessWriter) Header() http.Header {
+	return self.writer.Header()
+}
+
+func (self *accessWriter) WriteHeader(statusCode int) {
+	if 0 == self.statusCode {
+		self.statusCode = statusCode
+	}
+	self.writer.WriteHeader(statusCode)
+}
+
+func (self *accessWriter) Write(content []byte) (int, error) {
+	if 0 == self.statusCode {
+		self.statusCode = http.StatusOK
+	}
+	count, writeError := self.writer.Write(content)
+	self.bytes += int64(count)
+	return count, writeError
+}
+
+// Flush is required by server sent events.
+func (self *accessWriter) Flush() {
+	flusher, flusherOk := self.writer.(http.Flusher)
+	if flusherOk {
+		flusher.Flush()
+	}
+}
+
+// Hijack is required by web sockets.
+func (self *accessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, hijackerOk := self.writer.(http.Hijacker)
+	if !hijackerOk {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	conn, readWriter, hijackError := hijacker.Hijack()
+	if nil == hijackError && 0 == self.statusCode {
+		self.statusCode = http.StatusSwitchingProtocols
+	}
+
+	return conn, readWriter, hijackError
+}
+
+// Unwrap is used by http.ResponseController.
+func (self *accessWriter) Unwrap() http.ResponseWriter {
+	return self.writer
+}
+
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIdCreate takes the request id from the X-Request-Id header,
+// or generates a new one when the header is missing or invalid.
+func requestIdCreate(self *Server, httpRequest *http.Request) string {
+	requestId := httpRequest.Header.Get("X-Request-Id")
+	if requestIdPattern.MatchString(requestId) {
+		return requestId
+	}
+
+	uuidV4, uuidError := uuid.NewV4()
+	if uuidError != nil {
+		NotifierSendError(self.notifier, uuidError)
+		return ""
+	}
+
+	return uuidV4.String()
+}
+
+func accessEntryCreate(request *Request, writer *accessWriter, startedAt time.Time) AccessEntry {
+	statusCode := writer.statusCode
+	if 0 == statusCode {
+		statusCode = http.StatusOK
+	}
+
+	traceId := ""
+	if span := SpanFromContext(request.httpRequest.Context()); nil != span {
+		traceId = span.TraceId
+	}
+
+	return AccessEntry{
+		Time:      startedAt.UTC().Format(time.RFC3339Nano),
+		Method:    request.httpRequest.Method,
+		Path:      request.httpRequest.URL.Path,
+		ClientIp:  ReceiveClientIp(request),
+		Scheme:    ReceiveScheme(request),
+		Host:      ReceiveHost(request),
+		Status:    statusCode,
+		Bytes:     writer.bytes,
+		Duration:  float64(time.Since(startedAt).Microseconds()) / 1000,
+		SessionId: request.sessionId,
+		RequestId: request.id,
+		TraceId:   traceId,
+	}
+}
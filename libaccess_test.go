@@ -0,0 +1,195 @@
+package frizzante
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReceiveRequestId(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /id")
+		serve(func(request *Request, response *Response) {
+			SendEcho(response, ReceiveRequestId(request))
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	// Incoming.
+	expected := "incoming-id"
+	request, requestError := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/id", port), nil)
+	if requestError != nil {
+		test.Fatal(requestError)
+	}
+	request.Header.Set("X-Request-Id", expected)
+	response, doError := http.DefaultClient.Do(request)
+	if doError != nil {
+		test.Fatal(doError)
+	}
+	_ = response.Body.Close()
+
+	actual := response.Header.Get("X-Request-Id")
+	if actual != expected {
+		test.Fatalf("server was expected to echo request id '%s', received '%s' instead", expected, actual)
+	}
+
+	// Generated.
+	response, getError := http.Get(fmt.Sprintf("http://127.0.0.1:%d/id", port))
+	if getError != nil {
+		test.Fatal(getError)
+	}
+	_ = response.Body.Close()
+
+	actual = response.Header.Get("X-Request-Id")
+	if 36 != len(actual) {
+		test.Fatalf("server was expected to generate a uuid request id, received '%s' instead", actual)
+	}
+}
+
+func TestNotifierSendAccess(test *testing.T) {
+	accessFile, createError := os.CreateTemp("", "access")
+	if createError != nil {
+		test.Fatal(createError)
+	}
+	defer os.Remove(accessFile.Name())
+
+	server := ServerCreate()
+	port := NextNumber(8080)
+	notifier := NotifierCreate()
+	NotifierWithAccessFile(notifier, accessFile)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, notifier)
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /access")
+		serve(func(_ *Request, response *Response) {
+			SendStatus(response, http.StatusAccepted)
+			SendEcho(response, "hello")
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	request, requestError := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/access", port), nil)
+	if requestError != nil {
+		test.Fatal(requestError)
+	}
+	request.Header.Set("X-Request-Id", "access-id")
+	response, doError := http.DefaultClient.Do(request)
+	if doError != nil {
+		test.Fatal(doError)
+	}
+	_ = response.Body.Close()
+
+	contents, readError := os.ReadFile(accessFile.Name())
+	if readError != nil {
+		test.Fatal(readError)
+	}
+
+	var entry AccessEntry
+	unmarshalError := json.Unmarshal([]byte(strings.TrimSpace(string(contents))), &entry)
+	if unmarshalError != nil {
+		test.Fatal(unmarshalError)
+	}
+
+	if "GET" != entry.Method || "/access" != entry.Path || http.StatusAccepted != entry.Status || 5 != entry.Bytes || "access-id" != entry.RequestId {
+		test.Fatalf("unexpected access entry %+v", entry)
+	}
+}
+
+func TestNotifierSendErrorWithRequestId(test *testing.T) {
+	errorFile, createError := os.CreateTemp("", "error")
+	if createError != nil {
+		test.Fatal(createError)
+	}
+	defer os.Remove(errorFile.Name())
+
+	notifier := NotifierCreate()
+	notifier.errorFile = errorFile
+	NotifierSendError(notifierWithRequestId(notifier, "error-id"), errors.New("failure"))
+
+	contents, readError := os.ReadFile(errorFile.Name())
+	if readError != nil {
+		test.Fatal(readError)
+	}
+
+	expected := "[error-id] failure\n"
+	actual := string(contents)
+	if actual != expected {
+		test.Fatalf("notifier was expected to write '%s', received '%s' instead", expected, actual)
+	}
+}
+
+func TestReceiveNotifier(test *testing.T) {
+	errorFile, createError := os.CreateTemp("", "error")
+	if createError != nil {
+		test.Fatal(createError)
+	}
+	defer os.Remove(errorFile.Name())
+
+	notifier := NotifierCreate()
+	notifier.errorFile = errorFile
+
+	sqlite := sqliteCreate(test)
+	SqlWithNotifier(sqlite, notifier)
+
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, notifier)
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /notifier")
+		serve(func(request *Request, response *Response) {
+			NotifierSendError(ReceiveNotifier(request), errors.New("serve failure"))
+			SqlExecuteWithContext(sqlite, ReceiveContext(request), "INSERT INTO missing (id) VALUES (1)")
+			SendEcho(response, "")
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	request, requestError := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/notifier", port), nil)
+	if requestError != nil {
+		test.Fatal(requestError)
+	}
+	request.Header.Set("X-Request-Id", "notifier-id")
+	response, doError := http.DefaultClient.Do(request)
+	if doError != nil {
+		test.Fatal(doError)
+	}
+	_ = response.Body.Close()
+
+	contents, readError := os.ReadFile(errorFile.Name())
+	if readError != nil {
+		test.Fatal(readError)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	if 2 != len(lines) || "[notifier-id] serve failure" != lines[0] || !strings.HasPrefix(lines[1], "[notifier-id] no such table") {
+		test.Fatalf("errors were expected to be tagged with the request id, received '%s' instead", contents)
+	}
+}
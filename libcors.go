@@ -0,0 +1,296 @@
+package frizzante
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Cors struct {
+	origins        []string
+	methods        []string
+	headers        []string
+	exposedHeaders []string
+	credentials    bool
+	maxAge         time.Duration
+}
+
+// corsSafelistedHeaders lists the headers browsers send without asking permission to the server.
+var corsSafelistedHeaders = []string{"accept", "accept-language", "content-language", "content-type", "range"}
+
+// CorsCreate creates a cors configuration.
+//
+// By default, no origin is allowed, the allowed methods are those of the registered patterns
+// and only safelisted headers are allowed.
+func CorsCreate() *Cors {
+	return &Cors{
+		origins:        []string{},
+		methods:        []string{},
+		headers:        []string{},
+		exposedHeaders: []string{},
+		credentials:    false,
+		maxAge:         0,
+	}
+}
+
+// CorsWithOrigins sets the allowed origins, like "https://example.com".
+//
+// Use "*" to allow any origin, or "https://*.example.com" to allow any subdomain of example.com.
+//
+// Origins allowed only by "*" are never allowed to send credentials, see CorsWithCredentials.
+func CorsWithOrigins(self *Cors, origins ...string) {
+	self.origins = origins
+}
+
+// CorsWithMethods sets the allowed methods.
+//
+// When no method is set, the methods of the patterns matching the request are allowed.
+func CorsWithMethods(self *Cors, methods ...string) {
+	self.methods = methods
+}
+
+// CorsWithHeaders sets the request headers clients are allowed to send.
+func CorsWithHeaders(self *Cors, headers ...string) {
+	self.headers = headers
+}
+
+// CorsWithExposedHeaders sets the response headers clients are allowed to read.
+func CorsWithExposedHeaders(self *Cors, exposedHeaders ...string) {
+	self.exposedHeaders = exposedHeaders
+}
+
+// CorsWithCredentials sets whether clients are allowed to send cookies and authorization headers.
+//
+// Credentials are only allowed for origins listed explicitly or matched by a subdomain wildcard,
+// origins allowed only by "*" receive a literal "*" and no credentials.
+func CorsWithCredentials(self *Cors, credentials bool) {
+	self.credentials = credentials
+}
+
+// CorsWithMaxAge sets for how long clients can cache the response to a preflight request.
+func CorsWithMaxAge(self *Cors, maxAge time.Duration) {
+	self.maxAge = maxAge
+}
+
+// ServerWithCors sets the cors configuration of all apis.
+func ServerWithCors(self *Server, cors *Cors) {
+	self.cors = cors
+}
+
+// ServerWithRouteCors sets the cors configuration of the api mapped to pattern,
+// overriding the one set with ServerWithCors.
+func ServerWithRouteCors(self *Server, pattern string, cors *Cors) {
+	self.routeCors[pattern] = cors
+}
+
+// serverCors finds the cors configuration of a route.
+//
+// Pages are served to the origin of the server only, so they have no cors configuration.
+func serverCors(self *Server, route *Route) *Cors {
+	if nil == route || route.isPage {
+		return nil
+	}
+
+	cors, exists := self.routeCors[route.pattern]
+	if exists {
+		return cors
+	}
+
+	return self.cors
+}
+
+// corsAllowsOrigin checks if the configuration allows an origin.
+func corsAllowsOrigin(self *Cors, origin string) bool {
+	return slices.Contains(self.origins, "*") || corsListsOrigin(self, origin)
+}
+
+// corsListsOrigin checks if the configuration allows an origin explicitly,
+// either listing it or matching it with a subdomain wildcard, ignoring "*".
+func corsListsOrigin(self *Cors, origin string) bool {
+	for _, allowed := range self.origins {
+		if "*" == allowed {
+			continue
+		}
+
+		if origin == allowed {
+			return true
+		}
+
+		prefix, suffix, found := strings.Cut(allowed, "*")
+		if found && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) && len(origin) > len(prefix)+len(suffix) {
+			// The wildcard matches subdomains only.
+			middle := origin[len(prefix) : len(origin)-len(suffix)]
+			if !strings.ContainsAny(middle, "/:") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// corsAllowsHeader checks if the configuration allows a request header.
+func corsAllowsHeader(self *Cors, header string) bool {
+	header = strings.ToLower(strings.TrimSpace(header))
+	if slices.Contains(corsSafelistedHeaders, header) {
+		return true
+	}
+
+	for _, allowed := range self.headers {
+		if "*" == allowed || strings.ToLower(allowed) == header {
+			return true
+		}
+	}
+
+	return false
+}
+
+// corsIsSameOrigin checks if the origin of the request is the server itself.
+func corsIsSameOrigin(request *Request, origin string) bool {
+	originUrl, parseError := url.Parse(origin)
+	if parseError != nil {
+		return false
+	}
+
+	return originUrl.Scheme == ReceiveScheme(request) && originUrl.Host == ReceiveHost(request)
+}
+
+// corsRouteMethods lists the methods for which the request would be dispatched to a route,
+// ignoring the not found route.
+//
+// Routes registered with a GET method also allow the HEAD method.
+func corsRouteMethods(self *Server, httpRequest *http.Request, method string) []string {
+	candidates := []string{method, "HEAD"}
+	for _, route := range self.routes {
+		if "" != route.method && !slices.Contains(candidates, route.method) {
+			candidates = append(candidates, route.method)
+		}
+	}
+
+	notFound := routeFunctionName(notFoundServe)
+	var methods []string
+	for _, candidate := range candidates {
+		route := routeMatch(self, candidate, httpRequest.Host, httpRequest.URL.Path)
+		if nil == route || notFound == routeFunctionName(route.handler) {
+			continue
+		}
+		methods = append(methods, candidate)
+	}
+
+	slices.Sort(methods)
+	return methods
+}
+
+// corsSendOrigin sends the headers shared by preflight and actual responses.
+//
+// Origins allowed only by "*" are not reflected, they receive a literal "*" without credentials,
+// so that no site can read responses on behalf of the user.
+func corsSendOrigin(self *Cors, header http.Header, origin string) {
+	header.Add("Vary", "Origin")
+	if !corsListsOrigin(self, origin) {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+	if self.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// corsVerify applies the cors configuration of a route to an actual request.
+//
+// Cross origin requests from origins that are not allowed are rejected with status 403 Forbidden.
+func corsVerify(self *Server, route *Route, request *Request, response *Response) bool {
+	cors := serverCors(self, route)
+	if nil == cors {
+		return true
+	}
+
+	origin := ReceiveHeader(request, "Origin")
+	if "" == origin || corsIsSameOrigin(request, origin) {
+		return true
+	}
+
+	if !corsAllowsOrigin(cors, origin) {
+		SendForbidden(response)
+		SendEcho(response, "Origin Not Allowed")
+		return false
+	}
+
+	corsSendOrigin(cors, *response.header, origin)
+	if len(cors.exposedHeaders) > 0 {
+		SendHeader(response, "Access-Control-Expose-Headers", strings.Join(cors.exposedHeaders, ", "))
+	}
+
+	return true
+}
+
+// corsPreflight answers a preflight request for the route the actual request would match.
+//
+// It returns false if the request is not a preflight request, or if no route with a cors configuration matches.
+func corsPreflight(self *Server, request *Request, response *Response) bool {
+	httpRequest := request.httpRequest
+	origin := httpRequest.Header.Get("Origin")
+	method := httpRequest.Header.Get("Access-Control-Request-Method")
+	if http.MethodOptions != httpRequest.Method || "" == origin || "" == method {
+		return false
+	}
+
+	route := routeMatch(self, method, httpRequest.Host, httpRequest.URL.Path)
+	cors := serverCors(self, route)
+	if nil == cors {
+		return false
+	}
+
+	if !corsAllowsOrigin(cors, origin) {
+		SendForbidden(response)
+		SendEcho(response, "Origin Not Allowed")
+		return true
+	}
+
+	methods := cors.methods
+	if 0 == len(methods) {
+		methods = corsRouteMethods(self, httpRequest, method)
+	}
+
+	if !slices.Contains(methods, method) {
+		SendForbidden(response)
+		SendEcho(response, "Method Not Allowed")
+		return true
+	}
+
+	var headers []string
+	for _, header := range strings.Split(httpRequest.Header.Get("Access-Control-Request-Headers"), ",") {
+		if "" == strings.TrimSpace(header) {
+			continue
+		}
+
+		if !corsAllowsHeader(cors, header) {
+			SendForbidden(response)
+			SendEcho(response, "Header Not Allowed")
+			return true
+		}
+
+		headers = append(headers, strings.TrimSpace(header))
+	}
+
+	header := *response.header
+	corsSendOrigin(cors, header, origin)
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(headers) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if cors.maxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.FormatInt(int64(cors.maxAge.Seconds()), 10))
+	}
+
+	SendStatus(response, http.StatusNoContent)
+	SendEcho(response, "")
+	return true
+}
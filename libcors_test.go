@@ -0,0 +1,158 @@
+package frizzante
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCorsAllowsOrigin(test *testing.T) {
+	cors := CorsCreate()
+	CorsWithOrigins(cors, "https://example.com", "https://*.example.org")
+
+	allowed := []string{"https://example.com", "https://app.example.org", "https://a.b.example.org"}
+	for _, origin := range allowed {
+		if !corsAllowsOrigin(cors, origin) {
+			test.Fatalf("origin '%s' was expected to be allowed", origin)
+		}
+	}
+
+	rejected := []string{"http://example.com", "https://example.org", "https://evil.com/.example.org", "https://example.com.evil.com"}
+	for _, origin := range rejected {
+		if corsAllowsOrigin(cors, origin) {
+			test.Fatalf("origin '%s' was expected to be rejected", origin)
+		}
+	}
+}
+
+func TestServerWithCors(test *testing.T) {
+	cors := CorsCreate()
+	CorsWithOrigins(cors, "https://app.example.com")
+	CorsWithHeaders(cors, "Authorization")
+	CorsWithCredentials(cors, true)
+	CorsWithMaxAge(cors, 10*time.Minute)
+
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithCors(server, cors)
+	ServerWithRouteCors(server, "POST /private", CorsCreate())
+	for _, pattern := range []string{"POST /items", "DELETE /items", "POST /private", "POST /files/"} {
+		ServerWithApi(server, func(
+			route func(pattern string),
+			serve func(serveFunction func(req *Request, res *Response)),
+		) {
+			route(pattern)
+			serve(func(_ *Request, response *Response) {
+				SendEcho(response, "ok")
+			})
+		})
+	}
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	send := func(method string, path string, header map[string]string) *http.Response {
+		request, requestError := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+		if requestError != nil {
+			test.Fatal(requestError)
+		}
+		for key, value := range header {
+			request.Header.Set(key, value)
+		}
+		response, doError := http.DefaultClient.Do(request)
+		if doError != nil {
+			test.Fatal(doError)
+		}
+		_ = response.Body.Close()
+		return response
+	}
+
+	preflight := send("OPTIONS", "/items", map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "authorization, content-type",
+	})
+	if 204 != preflight.StatusCode {
+		test.Fatalf("preflight was expected to succeed, received status %d instead", preflight.StatusCode)
+	}
+
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Methods":     "DELETE, POST",
+		"Access-Control-Allow-Headers":     "authorization, content-type",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for key, value := range expected {
+		if value != preflight.Header.Get(key) {
+			test.Fatalf("preflight was expected to have header %s '%s', received '%s' instead", key, value, preflight.Header.Get(key))
+		}
+	}
+
+	rejectedHeader := send("OPTIONS", "/items", map[string]string{
+		"Origin":                         "https://app.example.com",
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "x-secret",
+	})
+	if 403 != rejectedHeader.StatusCode {
+		test.Fatalf("preflight with a header that is not allowed was expected to fail, received status %d instead", rejectedHeader.StatusCode)
+	}
+
+	actual := send("POST", "/items", map[string]string{"Origin": "https://app.example.com"})
+	if 200 != actual.StatusCode || "https://app.example.com" != actual.Header.Get("Access-Control-Allow-Origin") {
+		test.Fatalf("actual request was expected to succeed, received status %d and header %v instead", actual.StatusCode, actual.Header)
+	}
+
+	rejectedOrigin := send("POST", "/items", map[string]string{"Origin": "https://evil.com"})
+	if 403 != rejectedOrigin.StatusCode {
+		test.Fatalf("request from an origin that is not allowed was expected to fail, received status %d instead", rejectedOrigin.StatusCode)
+	}
+
+	sameOrigin := send("POST", "/items", map[string]string{"Origin": fmt.Sprintf("http://127.0.0.1:%d", port)})
+	if 200 != sameOrigin.StatusCode {
+		test.Fatalf("same origin request was expected to succeed, received status %d instead", sameOrigin.StatusCode)
+	}
+
+	otherScheme := send("POST", "/items", map[string]string{"Origin": fmt.Sprintf("https://127.0.0.1:%d", port)})
+	if 403 != otherScheme.StatusCode {
+		test.Fatalf("request from the same host with another scheme was expected to be cross origin, received status %d instead", otherScheme.StatusCode)
+	}
+
+	prefix := send("OPTIONS", "/files/report.pdf", map[string]string{
+		"Origin":                        "https://app.example.com",
+		"Access-Control-Request-Method": "POST",
+	})
+	if 204 != prefix.StatusCode || "POST" != prefix.Header.Get("Access-Control-Allow-Methods") {
+		test.Fatalf("preflight for a prefix route was expected to allow POST, received status %d and methods '%s' instead", prefix.StatusCode, prefix.Header.Get("Access-Control-Allow-Methods"))
+	}
+
+	private := send("OPTIONS", "/private", map[string]string{
+		"Origin":                        "https://app.example.com",
+		"Access-Control-Request-Method": "POST",
+	})
+	if 403 != private.StatusCode {
+		test.Fatalf("preflight for an api overriding the cors configuration was expected to fail, received status %d instead", private.StatusCode)
+	}
+}
+
+func TestCorsSendOrigin(test *testing.T) {
+	cors := CorsCreate()
+	CorsWithOrigins(cors, "*", "https://app.example.com")
+	CorsWithCredentials(cors, true)
+
+	header := http.Header{}
+	corsSendOrigin(cors, header, "https://evil.com")
+	if "*" != header.Get("Access-Control-Allow-Origin") || "" != header.Get("Access-Control-Allow-Credentials") {
+		test.Fatalf("origin allowed only by '*' was expected to receive '*' without credentials, received %v instead", header)
+	}
+
+	header = http.Header{}
+	corsSendOrigin(cors, header, "https://app.example.com")
+	if "https://app.example.com" != header.Get("Access-Control-Allow-Origin") || "true" != header.Get("Access-Control-Allow-Credentials") {
+		test.Fatalf("listed origin was expected to be reflected with credentials, received %v instead", header)
+	}
+}
@@ -0,0 +1,99 @@
+package frizzante
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+const csrfSessionKey = "csrf-token"
+const csrfFieldName = "csrf-token"
+const csrfHeaderName = "X-Csrf-Token"
+
+// ServerWithCsrf sets whether actions of indexes are protected against cross site request forgery,
+// which they are by default.
+//
+// Protected actions reject requests that don't carry the csrf token of the session
+// with status 403 Forbidden.
+//
+// The token is injected into the props of each page, the Form and Submit components send it automatically,
+// other clients can send it either as a "csrf-token" form field or as an "X-Csrf-Token" header.
+//
+// Json page responses carry the current token in the "X-Csrf-Token" header,
+// so that clients keep up with tokens renewed along with the session, see SessionRegenerate.
+func ServerWithCsrf(self *Server, csrf bool) {
+	self.csrf = csrf
+}
+
+// ServerWithRouteCsrf sets whether the route mapped to pattern is protected against cross site request forgery,
+// overriding the default.
+//
+// Apis are not protected by default.
+func ServerWithRouteCsrf(self *Server, pattern string, csrf bool) {
+	self.routeCsrf[pattern] = csrf
+}
+
+// serverCsrf checks if a route is protected against cross site request forgery.
+//
+// Safe methods are never protected, they must not change state.
+func serverCsrf(self *Server, route *Route, httpRequest *http.Request) bool {
+	switch httpRequest.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+
+	csrf, exists := self.routeCsrf[route.pattern]
+	if exists {
+		return csrf
+	}
+
+	return route.isPage && self.csrf
+}
+
+// ReceiveCsrfToken gets the csrf token of the session, creating it if it doesn't exist yet.
+func ReceiveCsrfToken(self *Request) string {
+	get, set, _ := SessionStart(self, self.response)
+	token, _ := get(csrfSessionKey, "").(string)
+	if "" != token {
+		return token
+	}
+
+	tokenBytes := make([]byte, 32)
+	_, readError := rand.Read(tokenBytes)
+	if readError != nil {
+		NotifierSendError(self.notifier, readError)
+		return ""
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(tokenBytes)
+	set(csrfSessionKey, token)
+	return token
+}
+
+// VerifyCsrfToken checks if the request carries the csrf token of the session.
+func VerifyCsrfToken(self *Request) bool {
+	received := ReceiveHeader(self, csrfHeaderName)
+	if "" == received {
+		received = ReceiveForm(self).Get(csrfFieldName)
+	}
+
+	if "" == received {
+		return false
+	}
+
+	expected := ReceiveCsrfToken(self)
+	return "" != expected && 1 == subtle.ConstantTimeCompare([]byte(received), []byte(expected))
+}
+
+// csrfVerify rejects requests to protected routes that don't carry the csrf token of the session
+// with status 403 Forbidden.
+func csrfVerify(self *Server, route *Route, request *Request, response *Response) bool {
+	if !serverCsrf(self, route, request.httpRequest) || VerifyCsrfToken(request) {
+		return true
+	}
+
+	SendForbidden(response)
+	SendEcho(response, "Invalid Csrf Token")
+	return false
+}
@@ -0,0 +1,195 @@
+package frizzante
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerWithCsrf(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /token")
+		serve(func(request *Request, response *Response) {
+			SendEcho(response, ReceiveCsrfToken(request))
+		})
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("POST /webhook")
+		serve(func(_ *Request, response *Response) {
+			SendEcho(response, "ok")
+		})
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("DELETE /account")
+		serve(func(_ *Request, response *Response) {
+			SendEcho(response, "ok")
+		})
+	})
+	ServerWithRouteCsrf(server, "DELETE /account", true)
+	ServerWithIndex(server, func(
+		route func(path string, page string),
+		show func(showFunction func(req *Request, res *Response, p *Page)),
+		action func(actionFunction func(req *Request, res *Response, p *Page)),
+	) {
+		route("/todos", "todos")
+		action(func(_ *Request, _ *Response, p *Page) {
+			PageWithData(p, "saved", true)
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	jar, jarError := cookiejar.New(nil)
+	if jarError != nil {
+		test.Fatal(jarError)
+	}
+	client := &http.Client{Jar: jar}
+	address := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	send := func(method string, path string, body string, header map[string]string) (int, string) {
+		request, requestError := http.NewRequest(method, address+path, strings.NewReader(body))
+		if requestError != nil {
+			test.Fatal(requestError)
+		}
+		for key, value := range header {
+			request.Header.Set(key, value)
+		}
+		response, doError := client.Do(request)
+		if doError != nil {
+			test.Fatal(doError)
+		}
+		defer response.Body.Close()
+		content, readError := io.ReadAll(response.Body)
+		if readError != nil {
+			test.Fatal(readError)
+		}
+		return response.StatusCode, string(content)
+	}
+
+	_, token := send("GET", "/token", "", nil)
+	if "" == token {
+		test.Fatal("csrf token was expected")
+	}
+
+	_, again := send("GET", "/token", "", nil)
+	if token != again {
+		test.Fatalf("csrf token was expected to be bound to the session, received '%s' and '%s'", token, again)
+	}
+
+	form := map[string]string{"Content-Type": "application/x-www-form-urlencoded", "Accept": "application/json"}
+
+	status, _ := send("POST", "/todos", url.Values{"title": {"milk"}}.Encode(), form)
+	if 403 != status {
+		test.Fatalf("action without csrf token was expected to be rejected, received status %d instead", status)
+	}
+
+	status, _ = send("POST", "/todos", url.Values{"csrf-token": {"forged"}}.Encode(), form)
+	if 403 != status {
+		test.Fatalf("action with an invalid csrf token was expected to be rejected, received status %d instead", status)
+	}
+
+	status, content := send("POST", "/todos", url.Values{"csrf-token": {token}}.Encode(), form)
+	if 200 != status || `{"saved":true}` != content {
+		test.Fatalf("action with csrf token was expected to succeed, received status %d and '%s' instead", status, content)
+	}
+
+	status, _ = send("POST", "/webhook", "", nil)
+	if 200 != status {
+		test.Fatalf("api was expected not to be protected by default, received status %d instead", status)
+	}
+
+	status, _ = send("DELETE", "/account", "", nil)
+	if 403 != status {
+		test.Fatalf("api opting in was expected to be protected, received status %d instead", status)
+	}
+
+	status, _ = send("DELETE", "/account", "", map[string]string{"X-Csrf-Token": token})
+	if 200 != status {
+		test.Fatalf("api opting in was expected to accept the csrf token header, received status %d instead", status)
+	}
+}
+
+func TestCsrfTokenAfterSessionRegenerate(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithIndex(server, func(
+		route func(path string, page string),
+		show func(showFunction func(req *Request, res *Response, p *Page)),
+		action func(actionFunction func(req *Request, res *Response, p *Page)),
+	) {
+		route("/account", "account")
+		action(func(request *Request, response *Response, p *Page) {
+			if "login" == ReceiveForm(request).Get("intent") {
+				SessionRegenerate(request, response)
+			}
+			PageWithData(p, "saved", true)
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	jar, jarError := cookiejar.New(nil)
+	if jarError != nil {
+		test.Fatal(jarError)
+	}
+	client := &http.Client{Jar: jar}
+	address := fmt.Sprintf("http://127.0.0.1:%d/account", port)
+
+	send := func(method string, form url.Values) *http.Response {
+		request, requestError := http.NewRequest(method, address, strings.NewReader(form.Encode()))
+		if requestError != nil {
+			test.Fatal(requestError)
+		}
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Accept", "application/json")
+		response, doError := client.Do(request)
+		if doError != nil {
+			test.Fatal(doError)
+		}
+		_ = response.Body.Close()
+		return response
+	}
+
+	previous := send("GET", nil).Header.Get("X-Csrf-Token")
+	if "" == previous {
+		test.Fatal("json page response was expected to carry the csrf token")
+	}
+
+	login := send("POST", url.Values{"csrf-token": {previous}, "intent": {"login"}})
+	current := login.Header.Get("X-Csrf-Token")
+	if 200 != login.StatusCode || "" == current || previous == current {
+		test.Fatalf("login was expected to renew the csrf token, received status %d and token '%s' instead", login.StatusCode, current)
+	}
+
+	if status := send("POST", url.Values{"csrf-token": {current}}).StatusCode; 200 != status {
+		test.Fatalf("post after login was expected to succeed with the renewed token, received status %d instead", status)
+	}
+
+	if status := send("POST", url.Values{"csrf-token": {previous}}).StatusCode; 403 != status {
+		test.Fatalf("post after login was expected to reject the previous token, received status %d instead", status)
+	}
+}
@@ -0,0 +1,57 @@
+package frizzante
+
+const flashSessionKey = "flash"
+
+// SendFlash sends a flash message, which is a value that survives
+// exactly one subsequent request of the client.
+//
+// Flash messages are exposed to the next page as the "flash" property of its data,
+// whether the page is rendered or sent as json,
+// and can be retrieved with ReceiveFlash.
+func SendFlash(self *Response, key string, value any) {
+	get, set, _ := SessionStart(self.request, self)
+	flash, _ := get(flashSessionKey, nil).(map[string]any)
+	if nil == flash {
+		flash = map[string]any{}
+	}
+
+	next := make(map[string]any, len(flash)+1)
+	for flashKey, flashValue := range flash {
+		next[flashKey] = flashValue
+	}
+	next[key] = value
+	set(flashSessionKey, next)
+}
+
+// ReceiveFlash gets the flash messages sent by the previous request of the client.
+func ReceiveFlash(self *Request) map[string]any {
+	SessionStart(self, self.response)
+	return self.flash
+}
+
+// flashConsume moves the flash messages of a session to the request starting it,
+// so that they don't survive the request.
+func flashConsume(request *Request, session *Session) {
+	flash, _ := session.get(flashSessionKey, nil).(map[string]any)
+	if nil == flash {
+		return
+	}
+
+	request.flash = flash
+	session.unset(flashSessionKey)
+}
+
+// flashPage finds the flash messages to expose to a page.
+//
+// Clients without a session can't have flash messages,
+// so no session is started for them.
+func flashPage(request *Request) map[string]any {
+	if nil == request.session {
+		_, cookieError := request.httpRequest.Cookie(request.server.sessionCookie.Name)
+		if cookieError != nil {
+			return nil
+		}
+	}
+
+	return ReceiveFlash(request)
+}
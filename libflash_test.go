@@ -0,0 +1,121 @@
+package frizzante
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+	"time"
+)
+
+func TestSendFlash(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("POST /")
+		serve(func(request *Request, response *Response) {
+			SendFlash(response, "message", "saved")
+			SendEcho(response, "")
+		})
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /")
+		serve(func(request *Request, response *Response) {
+			message, _ := ReceiveFlash(request)["message"].(string)
+			SendEcho(response, message)
+		})
+	})
+
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	send := func(method string) string {
+		request, _ := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d/", port), nil)
+		response, responseError := client.Do(request)
+		if responseError != nil {
+			test.Fatal(responseError)
+		}
+		defer response.Body.Close()
+		content, _ := io.ReadAll(response.Body)
+		return string(content)
+	}
+
+	send("POST")
+
+	content := send("GET")
+	if "saved" != content {
+		test.Fatalf("flash message was expected to survive the next request, received '%s' instead", content)
+	}
+
+	content = send("GET")
+	if "" != content {
+		test.Fatalf("flash message was expected to survive exactly one request, received '%s' instead", content)
+	}
+}
+
+func TestSendFlashWithJsonPage(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("POST /flash")
+		serve(func(request *Request, response *Response) {
+			SendFlash(response, "message", "saved")
+			SendEcho(response, "")
+		})
+	})
+	ServerWithIndex(server, func(
+		route func(path string, page string),
+		show func(showFunction func(req *Request, res *Response, p *Page)),
+		action func(actionFunction func(req *Request, res *Response, p *Page)),
+	) {
+		route("/account", "account")
+		show(func(request *Request, response *Response, p *Page) {})
+	})
+
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	send := func(method string, path string) string {
+		request, _ := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+		request.Header.Set("Accept", "application/json")
+		response, responseError := client.Do(request)
+		if responseError != nil {
+			test.Fatal(responseError)
+		}
+		defer response.Body.Close()
+		content, _ := io.ReadAll(response.Body)
+		return string(content)
+	}
+
+	send("POST", "/flash")
+
+	content := send("GET", "/account")
+	if `{"flash":{"message":"saved"}}` != content {
+		test.Fatalf("flash message was expected to be exposed to the json page, received '%s' instead", content)
+	}
+
+	content = send("GET", "/account")
+	if "{}" != content {
+		test.Fatalf("flash message was expected to be exposed exactly once, received '%s' instead", content)
+	}
+}
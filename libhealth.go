@@ -0,0 +1,209 @@
+package frizzante
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type HealthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type HealthCheckResult struct {
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_ms"`
+}
+
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+var healthStoppingError = errors.New("server is shutting down")
+
+// HealthCheckCreate creates a named health check.
+//
+// The check passes when the function returns nil.
+func HealthCheckCreate(name string, check func(ctx context.Context) error) *HealthCheck {
+	return &HealthCheck{
+		name:  name,
+		check: check,
+	}
+}
+
+// HealthCheckSql creates a health check that pings the database.
+func HealthCheckSql(sql *Sql) *HealthCheck {
+	return HealthCheckCreate("sql", func(ctx context.Context) error {
+		return sql.database.PingContext(ctx)
+	})
+}
+
+// HealthCheckRender creates a health check that verifies the server side render bundle can be loaded.
+func HealthCheckRender(server *Server) *HealthCheck {
+	return HealthCheckCreate("render", func(ctx context.Context) error {
+		_, bundleError := renderBundle(server.embeddedFileSystem)
+		return bundleError
+	})
+}
+
+// HealthCheckTemporaryDirectory creates a health check that verifies the temporary directory is writable.
+func HealthCheckTemporaryDirectory(server *Server) *HealthCheck {
+	return HealthCheckCreate("temporary_directory", func(ctx context.Context) error {
+		mkdirError := os.MkdirAll(server.temporaryDirectory, os.ModePerm)
+		if mkdirError != nil {
+			return mkdirError
+		}
+
+		file, createError := os.CreateTemp(server.temporaryDirectory, ".health-*")
+		if createError != nil {
+			return createError
+		}
+		fileName := file.Name()
+		defer os.Remove(fileName)
+
+		_, writeError := file.WriteString("ok")
+		closeError := file.Close()
+		if writeError != nil {
+			return writeError
+		}
+
+		return closeError
+	})
+}
+
+// ServerWithHealthChecks enables the liveness and readiness endpoints and adds checks to the readiness endpoint.
+//
+// The liveness endpoint always passes while the server is running,
+// the readiness endpoint passes only when all checks pass and the server is not shutting down.
+//
+// Both endpoints respond with a json HealthReport, using status 503 when failing.
+//
+// The endpoints are mapped the first time this is invoked, further invocations only add checks.
+func ServerWithHealthChecks(self *Server, checks ...*HealthCheck) {
+	self.healthChecks = append(self.healthChecks, checks...)
+	if !self.healthEnabled {
+		self.healthEnabled = true
+		serverMapHealth(self)
+	}
+}
+
+// ServerWithHealthPaths sets the paths of the liveness and readiness endpoints,
+// which default to /livez and /readyz.
+//
+// Paths must be set before enabling the endpoints with ServerWithHealthChecks.
+func ServerWithHealthPaths(self *Server, livenessPath string, readinessPath string) {
+	if self.healthEnabled {
+		NotifierSendError(self.notifier, errors.New("health paths must be set before enabling the health checks"))
+		return
+	}
+
+	self.livenessPath = livenessPath
+	self.readinessPath = readinessPath
+}
+
+// ServerWithHealthTimeout sets for how long the readiness endpoint waits for checks to complete,
+// checks that take longer fail.
+func ServerWithHealthTimeout(self *Server, healthTimeout time.Duration) {
+	self.healthTimeout = healthTimeout
+}
+
+// healthRun runs all checks concurrently.
+func healthRun(ctx context.Context, checks []*HealthCheck) HealthReport {
+	report := HealthReport{
+		Status: "pass",
+		Checks: map[string]HealthCheckResult{},
+	}
+
+	var lock sync.Mutex
+	var waiter sync.WaitGroup
+	for _, check := range checks {
+		waiter.Add(1)
+		go func() {
+			defer waiter.Done()
+			startedAt := time.Now()
+			checkError := healthRunCheck(ctx, check)
+
+			result := HealthCheckResult{
+				Status:   "pass",
+				Duration: float64(time.Since(startedAt).Microseconds()) / 1000,
+			}
+
+			if checkError != nil {
+				result.Status = "fail"
+				result.Error = checkError.Error()
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			report.Checks[check.name] = result
+			if checkError != nil {
+				report.Status = "fail"
+			}
+		}()
+	}
+	waiter.Wait()
+
+	return report
+}
+
+// healthRunCheck runs a check, giving up as soon as ctx is done.
+func healthRunCheck(ctx context.Context, check *HealthCheck) (checkError error) {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); nil != recovered {
+				done <- errors.New("health check panicked")
+			}
+		}()
+		done <- check.check(ctx)
+	}()
+
+	select {
+	case checkError = <-done:
+		return checkError
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func sendHealthReport(self *Response, report HealthReport) {
+	if "pass" != report.Status {
+		SendStatus(self, http.StatusServiceUnavailable)
+	}
+	SendHeader(self, "Cache-Control", "no-store")
+	SendJson(self, report)
+}
+
+// serverMapHealth maps the liveness and readiness endpoints.
+//
+// Unlike apis, these endpoints are not guarded, probes are usually not authenticated.
+func serverMapHealth(self *Server) {
+	liveness := func(_ *Request, response *Response) {
+		sendHealthReport(response, HealthReport{Status: "pass"})
+	}
+
+	readiness := func(request *Request, response *Response) {
+		if self.stopping.Load() {
+			sendHealthReport(response, HealthReport{
+				Status: "fail",
+				Checks: map[string]HealthCheckResult{
+					"shutdown": {Status: "fail", Error: healthStoppingError.Error()},
+				},
+			})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(ReceiveContext(request), self.healthTimeout)
+		defer cancel()
+		sendHealthReport(response, healthRun(ctx, self.healthChecks))
+	}
+
+	serverMapRoute(self, "GET "+self.livenessPath, &Route{handler: liveness, callback: liveness})
+	serverMapRoute(self, "GET "+self.readinessPath, &Route{handler: readiness, callback: readiness})
+}
@@ -0,0 +1,114 @@
+package frizzante
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestServerWithHealthChecks(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithApiGuard(server, func(req *Request, res *Response, pass func()) {
+		SendUnauthorized(res)
+	})
+	ServerWithHealthChecks(
+		server,
+		HealthCheckTemporaryDirectory(server),
+		HealthCheckCreate("queue", func(ctx context.Context) error {
+			return errors.New("queue is unreachable")
+		}),
+	)
+	var paths []string
+	for _, route := range ServerRoutes(server) {
+		paths = append(paths, route.Path)
+	}
+
+	if !slices.Contains(paths, "/livez") || !slices.Contains(paths, "/readyz") {
+		test.Fatalf("health endpoints were expected to be listed as soon as they're enabled, received %v instead", paths)
+	}
+
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	liveness, livenessError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/livez", port), nil)
+	if livenessError != nil {
+		test.Fatal(livenessError)
+	}
+
+	if `{"status":"pass"}` != liveness {
+		test.Fatalf("liveness was expected to pass, received '%s' instead", liveness)
+	}
+
+	readiness, readinessError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/readyz", port), nil)
+	if nil == readinessError {
+		test.Fatal("readiness was expected to fail")
+	}
+
+	var report HealthReport
+	unmarshalError := json.Unmarshal([]byte(readiness), &report)
+	if unmarshalError != nil {
+		test.Fatal(unmarshalError)
+	}
+
+	if "fail" != report.Status {
+		test.Fatalf("readiness was expected to fail, received '%s' instead", report.Status)
+	}
+
+	if "pass" != report.Checks["temporary_directory"].Status {
+		test.Fatalf("temporary directory check was expected to pass, received '%+v' instead", report.Checks["temporary_directory"])
+	}
+
+	if "queue is unreachable" != report.Checks["queue"].Error {
+		test.Fatalf("queue check was expected to fail, received '%+v' instead", report.Checks["queue"])
+	}
+}
+
+func TestServerStopFailsReadiness(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithShutdownDelay(server, 1*time.Second)
+	ServerWithHealthChecks(server)
+
+	stopped := make(chan bool)
+	go func() {
+		ServerStart(server)
+		stopped <- true
+	}()
+
+	time.Sleep(1 * time.Second)
+
+	_, readinessError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/readyz", port), nil)
+	if readinessError != nil {
+		test.Fatal(readinessError)
+	}
+
+	go ServerStop(server)
+	time.Sleep(200 * time.Millisecond)
+
+	readiness, readinessError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/readyz", port), nil)
+	if nil == readinessError {
+		test.Fatal("readiness was expected to fail while shutting down")
+	}
+
+	expected := `{"status":"fail","checks":{"shutdown":{"status":"fail","error":"server is shutting down","duration_ms":0}}}`
+	if expected != readiness {
+		test.Fatalf("readiness was expected to be '%s', received '%s' instead", expected, readiness)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		test.Fatal("server was expected to stop")
+	}
+}
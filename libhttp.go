@@ -2,6 +2,7 @@ package frizzante
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,13 +10,46 @@ import (
 
 var client http.Client
 
+// httpDo sends an http request using the shared client.
+//
+// When the context of the request carries a span, the request is traced with a child span,
+// which is propagated to the server using the traceparent header.
+func httpDo(request *http.Request) (*http.Response, error) {
+	ctx, span := spanStart(request.Context(), "HTTP "+request.Method, SpanKindClient)
+	if nil == span {
+		return client.Do(request)
+	}
+	defer SpanEnd(span)
+
+	request = request.WithContext(ctx)
+	request.Header.Set("traceparent", SpanTraceParent(span))
+	SpanWithAttribute(span, "http.request.method", request.Method)
+	SpanWithAttribute(span, "url.full", request.URL.String())
+
+	response, doError := client.Do(request)
+	if doError != nil {
+		SpanWithError(span, doError)
+		return nil, doError
+	}
+
+	SpanWithAttribute(span, "http.response.status_code", response.StatusCode)
+	return response, nil
+}
+
 // HttpGet sends an http request using the GET verb.
 func HttpGet(path string, header map[string]string) (string, error) {
+	return HttpGetWithContext(context.Background(), path, header)
+}
+
+// HttpGetWithContext sends an http request using the GET verb.
+//
+// The request is canceled as soon as ctx is done.
+func HttpGetWithContext(ctx context.Context, path string, header map[string]string) (string, error) {
 	if nil == header {
 		header = map[string]string{}
 	}
 
-	request, requestError := http.NewRequest("GET", path, nil)
+	request, requestError := http.NewRequestWithContext(ctx, "GET", path, nil)
 	if requestError != nil {
 		return "", requestError
 	}
@@ -24,7 +58,7 @@ func HttpGet(path string, header map[string]string) (string, error) {
 		request.Header.Set(key, value)
 	}
 
-	response, doError := client.Do(request)
+	response, doError := httpDo(request)
 	if doError != nil {
 		return "", doError
 	}
@@ -45,11 +79,18 @@ func HttpGet(path string, header map[string]string) (string, error) {
 
 // HttpDelete sends an http request using the DELETE verb.
 func HttpDelete(path string, header map[string]string) error {
+	return HttpDeleteWithContext(context.Background(), path, header)
+}
+
+// HttpDeleteWithContext sends an http request using the DELETE verb.
+//
+// The request is canceled as soon as ctx is done.
+func HttpDeleteWithContext(ctx context.Context, path string, header map[string]string) error {
 	if nil == header {
 		header = map[string]string{}
 	}
 
-	request, requestError := http.NewRequest("DELETE", path, nil)
+	request, requestError := http.NewRequestWithContext(ctx, "DELETE", path, nil)
 	if requestError != nil {
 		return requestError
 	}
@@ -58,7 +99,7 @@ func HttpDelete(path string, header map[string]string) error {
 		request.Header.Set(key, value)
 	}
 
-	response, doError := client.Do(request)
+	response, doError := httpDo(request)
 	if doError != nil {
 		return doError
 	}
@@ -73,11 +114,18 @@ func HttpDelete(path string, header map[string]string) error {
 
 // HttpPost sends an http request using the POST verb.
 func HttpPost(path string, contents string, header map[string]string) (string, error) {
+	return HttpPostWithContext(context.Background(), path, contents, header)
+}
+
+// HttpPostWithContext sends an http request using the POST verb.
+//
+// The request is canceled as soon as ctx is done.
+func HttpPostWithContext(ctx context.Context, path string, contents string, header map[string]string) (string, error) {
 	if nil == header {
 		header = map[string]string{}
 	}
 
-	request, requestError := http.NewRequest("POST", path, bytes.NewBuffer([]byte(contents)))
+	request, requestError := http.NewRequestWithContext(ctx, "POST", path, bytes.NewBuffer([]byte(contents)))
 	if requestError != nil {
 		return "", requestError
 	}
@@ -86,7 +134,7 @@ func HttpPost(path string, contents string, header map[string]string) (string, e
 		request.Header.Set(key, value)
 	}
 
-	response, doError := client.Do(request)
+	response, doError := httpDo(request)
 	if doError != nil {
 		return "", doError
 	}
@@ -108,11 +156,18 @@ func HttpPost(path string, contents string, header map[string]string) (string, e
 
 // HttpPut sends an http request using the PUT verb.
 func HttpPut(path string, header map[string]string, contents string) (string, error) {
+	return HttpPutWithContext(context.Background(), path, header, contents)
+}
+
+// HttpPutWithContext sends an http request using the PUT verb.
+//
+// The request is canceled as soon as ctx is done.
+func HttpPutWithContext(ctx context.Context, path string, header map[string]string, contents string) (string, error) {
 	if nil == header {
 		header = map[string]string{}
 	}
 
-	request, requestError := http.NewRequest("PUT", path, bytes.NewBuffer([]byte(contents)))
+	request, requestError := http.NewRequestWithContext(ctx, "PUT", path, bytes.NewBuffer([]byte(contents)))
 	if requestError != nil {
 		return "", requestError
 	}
@@ -121,7 +176,7 @@ func HttpPut(path string, header map[string]string, contents string) (string, er
 		request.Header.Set(key, value)
 	}
 
-	response, doError := client.Do(request)
+	response, doError := httpDo(request)
 	if doError != nil {
 		return "", doError
 	}
@@ -1,6 +1,7 @@
 package frizzante
 
 import (
+	"context"
 	"fmt"
 	"github.com/evanw/esbuild/pkg/api"
 	"rogchap.com/v8go"
@@ -41,12 +42,45 @@ func newJavaScriptContext(globals map[string]v8go.FunctionCallback) (*JavaScript
 //
 // Each global function will be injected into the context of the module automatically so that you can invoke them from the script.
 func JavaScriptRun(source string, globals map[string]v8go.FunctionCallback) (*v8go.Value, func(), error) {
+	return JavaScriptRunWithContext(context.Background(), source, globals)
+}
+
+// JavaScriptRunWithContext runs a javascript module, just like JavaScriptRun.
+//
+// Unlike JavaScriptRun, the execution of the script is terminated as soon as ctx is done,
+// in which case the error of ctx is returned.
+func JavaScriptRunWithContext(ctx context.Context, source string, globals map[string]v8go.FunctionCallback) (*v8go.Value, func(), error) {
+	contextError := ctx.Err()
+	if contextError != nil {
+		return nil, nil, contextError
+	}
+
 	js, createError := newJavaScriptContext(globals)
 	if createError != nil {
 		return nil, nil, createError
 	}
+
+	done := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-ctx.Done():
+			js.isolate.TerminateExecution()
+		case <-done:
+		}
+	}()
+
 	exports, runError := js.context.RunScript(source, "frizzante.js")
+	close(done)
+	<-watched
+
 	if runError != nil {
+		JavaScriptDestroy(js)
+		contextError = ctx.Err()
+		if contextError != nil {
+			return nil, nil, contextError
+		}
 		return nil, nil, runError
 	}
 
@@ -1,10 +1,13 @@
 package frizzante
 
 import (
+	"context"
+	"errors"
 	"github.com/evanw/esbuild/pkg/api"
 	"rogchap.com/v8go"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewJavaScriptContext(test *testing.T) {
@@ -121,3 +124,13 @@ func TestJavaScriptBundle(test *testing.T) {
 		test.Fatalf("script was expected to update the actual value to '%s', received '%s' instead.", expected, actual)
 	}
 }
+
+func TestJavaScriptRunWithContext(test *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, _, javaScriptError := JavaScriptRunWithContext(ctx, "while(true){}", map[string]v8go.FunctionCallback{})
+	if !errors.Is(javaScriptError, context.DeadlineExceeded) {
+		test.Fatalf("script was expected to be terminated with '%v', received '%v' instead", context.DeadlineExceeded, javaScriptError)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 //go:embed templates/*/**
@@ -232,12 +234,137 @@ func createPage(pageName string) {
 	}
 }
 
+func listRoutes(servers []*Server) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "METHOD\tPATTERN\tPAGE\tHANDLER\tGUARDS")
+	for _, server := range servers {
+		for _, route := range ServerRoutes(server) {
+			method := route.Method
+			if "" == method {
+				method = "*"
+			}
+
+			page := route.Page
+			if "" == page {
+				page = "-"
+			}
+
+			guards := strings.Join(route.Guards, ", ")
+			if "" == guards {
+				guards = "-"
+			}
+
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", method, route.Pattern, page, route.Handler, guards)
+		}
+	}
+
+	flushError := writer.Flush()
+	if flushError != nil {
+		panic(flushError)
+	}
+}
+
+func createMigration(directory string, migrationName string) {
+	if "" == migrationName {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Print("Name the migration: ")
+		migrationName, _ = reader.ReadString('\n')
+		if "" == migrationName {
+			createMigration(directory, migrationName)
+			return
+		}
+	}
+
+	up, down, createError := migrationCreate(directory, migrationName)
+	if createError != nil {
+		panic(createError)
+	}
+
+	fmt.Printf("Created migration `%s`.\n", up)
+	fmt.Printf("Created migration `%s`.\n", down)
+}
+
+func migrate(servers []*Server, mode string, name string, steps int) {
+	var migrator *Migrator
+	for _, server := range servers {
+		if nil != server.migrator {
+			migrator = server.migrator
+			break
+		}
+	}
+
+	if "create" == mode {
+		directory := "migrations"
+		if nil != migrator {
+			directory = migrator.directory
+		}
+		createMigration(directory, name)
+		return
+	}
+
+	if nil == migrator {
+		panic("no migrator found, use ServerWithMigrator to configure one")
+	}
+
+	switch mode {
+	case "up":
+		versions, upError := MigratorUp(migrator)
+		for _, version := range versions {
+			fmt.Printf("Applied migration `%s`.\n", version)
+		}
+		if upError != nil {
+			panic(upError)
+		}
+	case "down":
+		versions, downError := MigratorDown(migrator, steps)
+		for _, version := range versions {
+			fmt.Printf("Reverted migration `%s`.\n", version)
+		}
+		if downError != nil {
+			panic(downError)
+		}
+	case "status":
+		statuses, statusError := MigratorStatus(migrator)
+		if statusError != nil {
+			panic(statusError)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "-"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+
+		flushError := writer.Flush()
+		if flushError != nil {
+			panic(flushError)
+		}
+	default:
+		panic(fmt.Sprintf("unknown migrate mode `%s`, use up, down, status or create", mode))
+	}
+}
+
 // Make makes things.
-func Make() {
+//
+// Servers are optional, they're used by modes that inspect
+// a configured server, like -routes.
+//
+// Use -migrate with up, down, status or create to manage
+// the migrations of the first server configured with ServerWithMigrator,
+// -steps sets how many migrations -migrate down reverts, 1 by default,
+// -name names the migration created by -migrate create.
+func Make(servers ...*Server) {
 	api := flag.Bool("api", false, "")
 	index := flag.Bool("index", false, "")
 	guard := flag.Bool("guard", false, "")
 	page := flag.Bool("page", false, "")
+	routes := flag.Bool("routes", false, "")
+	migration := flag.String("migrate", "", "")
+	steps := flag.Int("steps", 1, "")
 	name := flag.String("name", "", "")
 	flag.Parse()
 
@@ -256,4 +383,12 @@ func Make() {
 	if *page {
 		createPage(*name)
 	}
+
+	if *routes {
+		listRoutes(servers)
+	}
+
+	if "" != *migration {
+		migrate(servers, *migration, *name, *steps)
+	}
 }
@@ -0,0 +1,370 @@
+package frizzante
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type metricKind string
+
+const (
+	metricKindCounter   metricKind = "counter"
+	metricKindGauge     metricKind = "gauge"
+	metricKindHistogram metricKind = "histogram"
+)
+
+var metricsDefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metricSeries struct {
+	labels  string
+	value   float64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+type metricFamily struct {
+	name    string
+	help    string
+	kind    metricKind
+	buckets []float64
+	series  map[string]*metricSeries
+}
+
+type Metrics struct {
+	lock       sync.Mutex
+	families   map[string]*metricFamily
+	helps      map[string]string
+	buckets    map[string][]float64
+	collectors []func(metrics *Metrics)
+}
+
+// MetricsCreate creates a metrics registry.
+func MetricsCreate() *Metrics {
+	return &Metrics{
+		families:   map[string]*metricFamily{},
+		helps:      map[string]string{},
+		buckets:    map[string][]float64{},
+		collectors: []func(metrics *Metrics){},
+	}
+}
+
+// MetricsWithHelp sets the help text of a metric.
+func MetricsWithHelp(self *Metrics, name string, help string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.helps[name] = help
+	family, exists := self.families[name]
+	if exists {
+		family.help = help
+	}
+}
+
+// MetricsWithBuckets sets the upper bounds of the buckets of a histogram.
+//
+// Buckets must be set before the first observation, otherwise they're ignored.
+func MetricsWithBuckets(self *Metrics, name string, buckets []float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	self.buckets[name] = sorted
+}
+
+// MetricsWithCollector adds a collector, a function that executes right before the metrics are written,
+// which is useful to update metrics whose values are read from somewhere else, like gauges of a pool.
+func MetricsWithCollector(self *Metrics, collector func(metrics *Metrics)) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.collectors = append(self.collectors, collector)
+}
+
+// metricsSeries finds or creates the series of a metric.
+//
+// It must be invoked while holding the lock.
+func metricsSeries(self *Metrics, name string, kind metricKind, labels map[string]string) *metricSeries {
+	family, exists := self.families[name]
+	if !exists {
+		buckets, bucketsExist := self.buckets[name]
+		if !bucketsExist {
+			buckets = metricsDefaultBuckets
+		}
+
+		family = &metricFamily{
+			name:    name,
+			help:    self.helps[name],
+			kind:    kind,
+			buckets: buckets,
+			series:  map[string]*metricSeries{},
+		}
+		self.families[name] = family
+	}
+
+	if family.kind != kind {
+		return nil
+	}
+
+	key := metricsLabels(labels)
+	series, seriesExists := family.series[key]
+	if !seriesExists {
+		series = &metricSeries{labels: key}
+		if metricKindHistogram == kind {
+			series.buckets = make([]uint64, len(family.buckets))
+		}
+		family.series[key] = series
+	}
+
+	return series
+}
+
+// MetricsCounterAdd adds value to a counter.
+//
+// Counters can only increase, negative values are ignored.
+func MetricsCounterAdd(self *Metrics, name string, labels map[string]string, value float64) {
+	if value < 0 {
+		return
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	series := metricsSeries(self, name, metricKindCounter, labels)
+	if nil == series {
+		return
+	}
+	series.value += value
+}
+
+// MetricsGaugeSet sets the value of a gauge.
+func MetricsGaugeSet(self *Metrics, name string, labels map[string]string, value float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	series := metricsSeries(self, name, metricKindGauge, labels)
+	if nil == series {
+		return
+	}
+	series.value = value
+}
+
+// MetricsGaugeAdd adds value to a gauge, value can be negative.
+func MetricsGaugeAdd(self *Metrics, name string, labels map[string]string, value float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	series := metricsSeries(self, name, metricKindGauge, labels)
+	if nil == series {
+		return
+	}
+	series.value += value
+}
+
+// MetricsHistogramObserve observes a value in a histogram.
+func MetricsHistogramObserve(self *Metrics, name string, labels map[string]string, value float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	series := metricsSeries(self, name, metricKindHistogram, labels)
+	if nil == series {
+		return
+	}
+
+	family := self.families[name]
+	for index, upperBound := range family.buckets {
+		if value <= upperBound {
+			series.buckets[index]++
+		}
+	}
+	series.sum += value
+	series.count++
+}
+
+// MetricsWrite writes all metrics using the prometheus text exposition format.
+func MetricsWrite(self *Metrics, writer io.Writer) error {
+	self.lock.Lock()
+	collectors := append([]func(metrics *Metrics){}, self.collectors...)
+	self.lock.Unlock()
+
+	for _, collector := range collectors {
+		collector(self)
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	var names []string
+	for name := range self.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		family := self.families[name]
+		if "" != family.help {
+			builder.WriteString(fmt.Sprintf("# HELP %s %s\n", name, metricsEscape(family.help, false)))
+		}
+		builder.WriteString(fmt.Sprintf("# TYPE %s %s\n", name, family.kind))
+
+		var keys []string
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			series := family.series[key]
+			if metricKindHistogram != family.kind {
+				builder.WriteString(fmt.Sprintf("%s%s %s\n", name, metricsBraces(key), metricsFormat(series.value)))
+				continue
+			}
+
+			for index, upperBound := range family.buckets {
+				bucketLabels := metricsJoin(key, fmt.Sprintf("le=\"%s\"", metricsFormat(upperBound)))
+				builder.WriteString(fmt.Sprintf("%s_bucket{%s} %d\n", name, bucketLabels, series.buckets[index]))
+			}
+			builder.WriteString(fmt.Sprintf("%s_bucket{%s} %d\n", name, metricsJoin(key, "le=\"+Inf\""), series.count))
+			builder.WriteString(fmt.Sprintf("%s_sum%s %s\n", name, metricsBraces(key), metricsFormat(series.sum)))
+			builder.WriteString(fmt.Sprintf("%s_count%s %d\n", name, metricsBraces(key), series.count))
+		}
+	}
+
+	_, writeError := io.WriteString(writer, builder.String())
+	return writeError
+}
+
+// metricsLabels serializes labels, sorted by name.
+func metricsLabels(labels map[string]string) string {
+	var names []string
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, metricsEscape(labels[name], true)))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func metricsEscape(value string, quotes bool) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	if quotes {
+		value = strings.ReplaceAll(value, `"`, `\"`)
+	}
+	return value
+}
+
+func metricsBraces(labels string) string {
+	if "" == labels {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func metricsJoin(labels string, label string) string {
+	if "" == labels {
+		return label
+	}
+	return labels + "," + label
+}
+
+func metricsFormat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	if math.IsInf(value, -1) {
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// metricsDescribeServer sets the help texts of all metrics fed by the server.
+func metricsDescribeServer(self *Metrics) {
+	MetricsWithHelp(self, "frizzante_http_requests_total", "Total number of http requests handled.")
+	MetricsWithHelp(self, "frizzante_http_request_duration_seconds", "Duration of http requests in seconds.")
+	MetricsWithHelp(self, "frizzante_render_duration_seconds", "Duration of server side renders in seconds.")
+	MetricsWithHelp(self, "frizzante_web_sockets_active", "Number of active web sockets.")
+	MetricsWithHelp(self, "frizzante_server_sent_events_active", "Number of active server sent events streams.")
+	MetricsWithHelp(self, "frizzante_sessions_active", "Number of active sessions.")
+	MetricsWithHelp(self, "frizzante_sessions_created_total", "Total number of sessions created.")
+	MetricsWithHelp(self, "frizzante_sql_connections_max_open", "Maximum number of open sql connections.")
+	MetricsWithHelp(self, "frizzante_sql_connections_open", "Number of open sql connections.")
+	MetricsWithHelp(self, "frizzante_sql_connections_in_use", "Number of sql connections in use.")
+	MetricsWithHelp(self, "frizzante_sql_connections_idle", "Number of idle sql connections.")
+	MetricsWithHelp(self, "frizzante_sql_connections_waited_total", "Total number of sql connections waited for.")
+	MetricsWithHelp(self, "frizzante_sql_connections_wait_seconds_total", "Total time spent waiting for sql connections in seconds.")
+	MetricsWithHelp(self, "frizzante_sql_statements_cached", "Number of cached sql prepared statements.")
+	MetricsWithHelp(self, "frizzante_sql_statement_cache_hits_total", "Total number of sql prepared statements found in the cache.")
+	MetricsWithHelp(self, "frizzante_sql_statement_cache_misses_total", "Total number of sql prepared statements missing from the cache.")
+}
+
+// metricsCollectServer adds a collector that feeds the metrics read from the server,
+// like the statistics of its sql wrapper, see ServerWithSql.
+func metricsCollectServer(self *Server, metrics *Metrics) {
+	var lock sync.Mutex
+	var previous SqlStatistics
+	var previousSql *Sql
+	MetricsWithCollector(metrics, func(metrics *Metrics) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if nil == self.sql {
+			return
+		}
+
+		if previousSql != self.sql {
+			previous = SqlStatistics{}
+			previousSql = self.sql
+		}
+
+		current := SqlStats(self.sql)
+		MetricsGaugeSet(metrics, "frizzante_sql_connections_max_open", nil, float64(current.MaxOpenConnections))
+		MetricsGaugeSet(metrics, "frizzante_sql_connections_open", nil, float64(current.OpenConnections))
+		MetricsGaugeSet(metrics, "frizzante_sql_connections_in_use", nil, float64(current.InUse))
+		MetricsGaugeSet(metrics, "frizzante_sql_connections_idle", nil, float64(current.Idle))
+		MetricsGaugeSet(metrics, "frizzante_sql_statements_cached", nil, float64(current.StatementsCached))
+
+		// Statistics are totals, counters are fed the difference since the previous collection.
+		MetricsCounterAdd(metrics, "frizzante_sql_connections_waited_total", nil, float64(current.WaitCount-previous.WaitCount))
+		MetricsCounterAdd(metrics, "frizzante_sql_connections_wait_seconds_total", nil, (current.WaitDuration - previous.WaitDuration).Seconds())
+		MetricsCounterAdd(metrics, "frizzante_sql_statement_cache_hits_total", nil, float64(current.StatementCacheHits-previous.StatementCacheHits))
+		MetricsCounterAdd(metrics, "frizzante_sql_statement_cache_misses_total", nil, float64(current.StatementCacheMisses-previous.StatementCacheMisses))
+		previous = current
+	})
+}
+
+// ServerWithMetrics sets the metrics registry fed by the server.
+func ServerWithMetrics(self *Server, metrics *Metrics) {
+	metricsDescribeServer(metrics)
+	metricsCollectServer(self, metrics)
+	self.metrics = metrics
+}
+
+// ServerWithMetricsPath exposes the metrics of the server at the given path,
+// using the prometheus text exposition format.
+func ServerWithMetricsPath(self *Server, path string) {
+	ServerWithApi(self, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET " + path)
+		serve(func(request *Request, response *Response) {
+			var builder strings.Builder
+			writeError := MetricsWrite(self.metrics, &builder)
+			if writeError != nil {
+				NotifierSendError(request.notifier, writeError)
+				SendStatus(response, 500)
+				return
+			}
+			SendHeader(response, "Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+			SendEcho(response, builder.String())
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package frizzante
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsWrite(test *testing.T) {
+	metrics := MetricsCreate()
+	MetricsWithHelp(metrics, "jobs_total", "Total number of jobs.")
+	MetricsWithBuckets(metrics, "job_duration_seconds", []float64{1, 0.5})
+	MetricsCounterAdd(metrics, "jobs_total", map[string]string{"queue": "default"}, 2)
+	MetricsCounterAdd(metrics, "jobs_total", map[string]string{"queue": "default"}, 1)
+	MetricsGaugeSet(metrics, "workers", nil, 4)
+	MetricsGaugeAdd(metrics, "workers", nil, -1)
+	MetricsHistogramObserve(metrics, "job_duration_seconds", nil, 0.7)
+	MetricsWithCollector(metrics, func(metrics *Metrics) {
+		MetricsGaugeSet(metrics, "collected", nil, 1)
+	})
+
+	var builder strings.Builder
+	writeError := MetricsWrite(metrics, &builder)
+	if writeError != nil {
+		test.Fatal(writeError)
+	}
+	actual := builder.String()
+
+	expected := []string{
+		"# TYPE collected gauge\ncollected 1\n",
+		"# TYPE job_duration_seconds histogram\n" +
+			"job_duration_seconds_bucket{le=\"0.5\"} 0\n" +
+			"job_duration_seconds_bucket{le=\"1\"} 1\n" +
+			"job_duration_seconds_bucket{le=\"+Inf\"} 1\n" +
+			"job_duration_seconds_sum 0.7\n" +
+			"job_duration_seconds_count 1\n",
+		"# HELP jobs_total Total number of jobs.\n# TYPE jobs_total counter\njobs_total{queue=\"default\"} 3\n",
+		"# TYPE workers gauge\nworkers 3\n",
+	}
+
+	for _, part := range expected {
+		if !strings.Contains(actual, part) {
+			test.Fatalf("metrics were expected to contain '%s', received '%s' instead", part, actual)
+		}
+	}
+}
+
+func TestServerWithMetricsPath(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithMetricsPath(server, "/metrics")
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /hello")
+		serve(func(_ *Request, response *Response) {
+			SendEcho(response, "hello")
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	_, getError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/hello", port), nil)
+	if getError != nil {
+		test.Fatal(getError)
+	}
+
+	actual, getError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/metrics", port), nil)
+	if getError != nil {
+		test.Fatal(getError)
+	}
+
+	expected := []string{
+		"frizzante_http_requests_total{method=\"GET\",pattern=\"GET /hello\",status=\"200\"} 1",
+		"frizzante_http_request_duration_seconds_count{method=\"GET\",pattern=\"GET /hello\"} 1",
+	}
+
+	for _, part := range expected {
+		if !strings.Contains(actual, part) {
+			test.Fatalf("metrics were expected to contain '%s', received '%s' instead", part, actual)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package frizzante
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	sql         *Sql
+	fileSystem  fs.FS
+	directory   string
+	table       string
+	lockTimeout time.Duration
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_\-]+)\.(up|down)\.sql$`)
+
+// MigratorCreate creates a migrator, which applies versioned migrations through sql.
+//
+// By default, migrations are read from the "migrations" directory,
+// and applied migrations are tracked in the "schema_migrations" table.
+//
+// Each migration is made of two files, named after its version and name,
+// like "20240102150405_create_users.up.sql" and "20240102150405_create_users.down.sql".
+// Use Make with -migrate create to generate them.
+//
+// Migrations are executed as they're written, their placeholders are not rewritten, see SqlWithDialect.
+// Migrations made of multiple statements require a driver that can execute them at once,
+// like Mysql with "multiStatements=true" in its data source name.
+func MigratorCreate(sql *Sql) *Migrator {
+	return &Migrator{
+		sql:         sql,
+		fileSystem:  os.DirFS("."),
+		directory:   "migrations",
+		table:       "schema_migrations",
+		lockTimeout: time.Minute,
+	}
+}
+
+// MigratorWithFileSystem sets the file system and the directory migrations are read from,
+// like an embed.FS, or os.DirFS to read them from disk.
+func MigratorWithFileSystem(self *Migrator, fileSystem fs.FS, directory string) {
+	self.fileSystem = fileSystem
+	self.directory = directory
+}
+
+// MigratorWithTable sets the table applied migrations are tracked in.
+func MigratorWithTable(self *Migrator, table string) {
+	self.table = table
+}
+
+// MigratorWithLockTimeout sets for how long a migrator waits for other migrators to complete.
+func MigratorWithLockTimeout(self *Migrator, lockTimeout time.Duration) {
+	self.lockTimeout = lockTimeout
+}
+
+// ServerWithMigrator sets the migrator used by Make with -migrate.
+func ServerWithMigrator(self *Server, migrator *Migrator) {
+	self.migrator = migrator
+}
+
+// MigratorMigrations reads all migrations, sorted by version.
+func MigratorMigrations(self *Migrator) ([]*Migration, error) {
+	entries, readError := fs.ReadDir(self.fileSystem, self.directory)
+	if readError != nil {
+		return nil, readError
+	}
+
+	migrations := map[string]*Migration{}
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || nil == matches {
+			continue
+		}
+
+		content, contentError := fs.ReadFile(self.fileSystem, path.Join(self.directory, entry.Name()))
+		if contentError != nil {
+			return nil, contentError
+		}
+
+		version, name, direction := matches[1], matches[2], matches[3]
+		migration, exists := migrations[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			migrations[version] = migration
+		}
+
+		if name != migration.Name {
+			return nil, fmt.Errorf("migration %s is named both `%s` and `%s`", version, migration.Name, name)
+		}
+
+		if "up" == direction {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	sorted := make([]*Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		sorted = append(sorted, migration)
+	}
+
+	slices.SortFunc(sorted, func(a *Migration, b *Migration) int {
+		if len(a.Version) != len(b.Version) {
+			return len(a.Version) - len(b.Version)
+		}
+		return strings.Compare(a.Version, b.Version)
+	})
+
+	return sorted, nil
+}
+
+// migratorPrepare creates the table applied migrations are tracked in.
+func migratorPrepare(self *Migrator, ctx context.Context) error {
+	if !sqlIdentifierPattern.MatchString(self.table) {
+		return fmt.Errorf("migrations table `%s` is not a valid table name", self.table)
+	}
+
+	_, err := SqlTryExecuteWithContext(self.sql, ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (version VARCHAR(32) NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at BIGINT NOT NULL)",
+		self.table,
+	))
+	return err
+}
+
+// migratorApplied finds when each applied migration has been applied, by version.
+func migratorApplied(self *Migrator, ctx context.Context, connection *sql.Conn) (map[string]time.Time, error) {
+	applied := map[string]time.Time{}
+	err := migratorTransaction(self, ctx, connection, func(tx *SqlTx) error {
+		rows, findError := SqlTxFind(tx, fmt.Sprintf("SELECT version, applied_at FROM %s", self.table))
+		if findError != nil {
+			return findError
+		}
+		defer SqlRowsClose(rows)
+
+		var version string
+		var appliedAt int64
+		for SqlRowsNext(rows, &version, &appliedAt) {
+			applied[version] = time.UnixMilli(appliedAt)
+		}
+		return SqlRowsError(rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// migratorTransaction runs fn within a transaction on the locked connection,
+// or on the pool of the database if there's no such connection.
+//
+// Transactions must use the locked connection, otherwise they would wait forever
+// for a second connection when the pool is limited to one, see SqlWithMaxOpenConnections.
+func migratorTransaction(self *Migrator, ctx context.Context, connection *sql.Conn, fn func(tx *SqlTx) error) error {
+	if nil == connection {
+		return SqlTransactionWithContext(self.sql, ctx, fn)
+	}
+	return sqlTransaction(self.sql, ctx, connection.BeginTx, fn)
+}
+
+// migrationExecute executes the body of a migration as it's written, without rewriting its placeholders.
+func migrationExecute(tx *SqlTx, body string) error {
+	_, err := tx.transaction.ExecContext(tx.ctx, body)
+	return err
+}
+
+// migratorLock prevents other migrators from migrating the database until unlock is invoked.
+//
+// Mysql and Postgresql use advisory locks, which are released automatically if the migrator dies,
+// they belong to the returned connection, which must be used to migrate the database.
+// Sqlite uses a lock table instead, in which case the returned connection is nil.
+func migratorLock(self *Migrator, ctx context.Context) (connection *sql.Conn, unlock func(), err error) {
+	ctx, cancel := context.WithTimeout(ctx, self.lockTimeout)
+	defer cancel()
+
+	name := "frizzante_" + self.table
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(name))
+	key := int64(hash.Sum64() >> 1)
+
+	if SqlDialectSqlite == self.sql.dialect {
+		lockTable := self.table + "_lock"
+		_, err = SqlTryExecuteWithContext(self.sql, ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER NOT NULL PRIMARY KEY)", lockTable))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for {
+			_, err = SqlTryExecuteWithContext(self.sql, ctx, fmt.Sprintf("INSERT INTO %s (id) VALUES (1)", lockTable))
+			if nil == err {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, nil, fmt.Errorf("could not lock migrations, remove the row of table `%s` if no other migrator is running: %w", lockTable, ctx.Err())
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+
+		return nil, func() {
+			_, unlockError := SqlTryExecute(self.sql, fmt.Sprintf("DELETE FROM %s", lockTable))
+			if unlockError != nil {
+				NotifierSendError(self.sql.notifier, unlockError)
+			}
+		}, nil
+	}
+
+	// Advisory locks belong to the connection that acquired them.
+	connection, err = self.sql.database.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var locked sql.NullInt64
+	lockQuery, unlockQuery, lockProps := "SELECT GET_LOCK(?, ?)", "SELECT RELEASE_LOCK(?)", []any{name, int64(self.lockTimeout.Seconds())}
+	if SqlDialectPostgresql == self.sql.dialect {
+		lockQuery, unlockQuery, lockProps = "SELECT 1 FROM pg_advisory_lock($1)", "SELECT pg_advisory_unlock($1)", []any{key}
+	}
+
+	err = connection.QueryRowContext(ctx, lockQuery, lockProps...).Scan(&locked)
+	if nil == err && 1 != locked.Int64 {
+		err = errors.New("could not lock migrations, another migrator is running")
+	}
+
+	if err != nil {
+		_ = connection.Close()
+		return nil, nil, err
+	}
+
+	return connection, func() {
+		unlockProp := lockProps[0]
+		_, unlockError := connection.ExecContext(context.Background(), unlockQuery, unlockProp)
+		if unlockError != nil {
+			NotifierSendError(self.sql.notifier, unlockError)
+		}
+		_ = connection.Close()
+	}, nil
+}
+
+// MigratorUp applies all pending migrations, in order of version.
+//
+// Each migration is applied within its own transaction,
+// migrating stops at the first migration that fails.
+//
+// It returns the versions of the applied migrations.
+func MigratorUp(self *Migrator) ([]string, error) {
+	return MigratorUpWithContext(self, context.Background())
+}
+
+// MigratorUpWithContext works like MigratorUp, except migrating stops as soon as ctx is done.
+func MigratorUpWithContext(self *Migrator, ctx context.Context) (versions []string, err error) {
+	migrations, migrationsError := MigratorMigrations(self)
+	if migrationsError != nil {
+		return nil, migrationsError
+	}
+
+	prepareError := migratorPrepare(self, ctx)
+	if prepareError != nil {
+		return nil, prepareError
+	}
+
+	connection, unlock, lockError := migratorLock(self, ctx)
+	if lockError != nil {
+		return nil, lockError
+	}
+	defer unlock()
+
+	// Cached statements may refer to the previous schema.
+	defer sqlStatementCacheClear(self.sql)
+
+	applied, appliedError := migratorApplied(self, ctx, connection)
+	if appliedError != nil {
+		return nil, appliedError
+	}
+
+	for _, migration := range migrations {
+		if _, exists := applied[migration.Version]; exists {
+			continue
+		}
+
+		err = migratorTransaction(self, ctx, connection, func(tx *SqlTx) error {
+			upError := migrationExecute(tx, migration.Up)
+			if upError != nil {
+				return upError
+			}
+
+			_, insertError := SqlTxExecute(
+				tx,
+				fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (?, ?, ?)", self.table),
+				migration.Version, migration.Name, time.Now().UnixMilli(),
+			)
+			return insertError
+		})
+
+		if err != nil {
+			return versions, fmt.Errorf("could not apply migration %s_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		versions = append(versions, migration.Version)
+	}
+
+	return versions, nil
+}
+
+// MigratorDown reverts the last steps applied migrations, in reverse order of version.
+//
+// Each migration is reverted within its own transaction,
+// reverting stops at the first migration that fails.
+//
+// It returns the versions of the reverted migrations.
+func MigratorDown(self *Migrator, steps int) ([]string, error) {
+	return MigratorDownWithContext(self, context.Background(), steps)
+}
+
+// MigratorDownWithContext works like MigratorDown, except reverting stops as soon as ctx is done.
+func MigratorDownWithContext(self *Migrator, ctx context.Context, steps int) (versions []string, err error) {
+	migrations, migrationsError := MigratorMigrations(self)
+	if migrationsError != nil {
+		return nil, migrationsError
+	}
+
+	prepareError := migratorPrepare(self, ctx)
+	if prepareError != nil {
+		return nil, prepareError
+	}
+
+	connection, unlock, lockError := migratorLock(self, ctx)
+	if lockError != nil {
+		return nil, lockError
+	}
+	defer unlock()
+
+	// Cached statements may refer to the previous schema.
+	defer sqlStatementCacheClear(self.sql)
+
+	applied, appliedError := migratorApplied(self, ctx, connection)
+	if appliedError != nil {
+		return nil, appliedError
+	}
+
+	for index := len(migrations) - 1; index >= 0 && len(versions) < steps; index-- {
+		migration := migrations[index]
+		if _, exists := applied[migration.Version]; !exists {
+			continue
+		}
+
+		err = migratorTransaction(self, ctx, connection, func(tx *SqlTx) error {
+			if "" != strings.TrimSpace(migration.Down) {
+				downError := migrationExecute(tx, migration.Down)
+				if downError != nil {
+					return downError
+				}
+			}
+
+			_, deleteError := SqlTxExecute(tx, fmt.Sprintf("DELETE FROM %s WHERE version = ?", self.table), migration.Version)
+			return deleteError
+		})
+
+		if err != nil {
+			return versions, fmt.Errorf("could not revert migration %s_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		versions = append(versions, migration.Version)
+	}
+
+	return versions, nil
+}
+
+// MigratorStatus finds which migrations have been applied.
+func MigratorStatus(self *Migrator) ([]MigrationStatus, error) {
+	ctx := context.Background()
+	migrations, migrationsError := MigratorMigrations(self)
+	if migrationsError != nil {
+		return nil, migrationsError
+	}
+
+	prepareError := migratorPrepare(self, ctx)
+	if prepareError != nil {
+		return nil, prepareError
+	}
+
+	applied, appliedError := migratorApplied(self, ctx, nil)
+	if appliedError != nil {
+		return nil, appliedError
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, exists := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   exists,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// migrationCreate writes the up and down files of a new migration to directory,
+// versioned with the current time.
+func migrationCreate(directory string, name string) (up string, down string, err error) {
+	name = strings.Trim(strings.ReplaceAll(name, "-", "_"), "\r\n\t ")
+	name = strings.ReplaceAll(name, " ", "_")
+	if !migrationFilePattern.MatchString("0_" + name + ".up.sql") {
+		return "", "", fmt.Errorf("migration name `%s` is not valid", name)
+	}
+
+	mkdirError := os.MkdirAll(directory, os.ModePerm)
+	if mkdirError != nil {
+		return "", "", mkdirError
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+	up = filepath.Join(directory, fmt.Sprintf("%s_%s.up.sql", version, name))
+	down = filepath.Join(directory, fmt.Sprintf("%s_%s.down.sql", version, name))
+
+	if Exists(up) || Exists(down) {
+		return "", "", fmt.Errorf("migration `%s_%s` already exists", version, name)
+	}
+
+	err = os.WriteFile(up, []byte("-- Write the migration here.\n"), os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = os.WriteFile(down, []byte("-- Write the reverse of the migration here.\n"), os.ModePerm)
+	if err != nil {
+		return "", "", err
+	}
+
+	return up, down, nil
+}
@@ -0,0 +1,99 @@
+package frizzante
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigratorUp(test *testing.T) {
+	sqlite := sqliteCreate(test)
+
+	fileSystem := fstest.MapFS{
+		"migrations/20240101000000_create_users.up.sql":   {Data: []byte("CREATE TABLE users (name TEXT)")},
+		"migrations/20240101000000_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"migrations/20240102000000_create_posts.up.sql":   {Data: []byte("CREATE TABLE posts (title TEXT)")},
+		"migrations/20240102000000_create_posts.down.sql": {Data: []byte("DROP TABLE posts")},
+	}
+
+	migrator := MigratorCreate(sqlite)
+	MigratorWithFileSystem(migrator, fileSystem, "migrations")
+
+	versions, upError := MigratorUp(migrator)
+	if upError != nil {
+		test.Fatal(upError)
+	}
+
+	if 2 != len(versions) || "20240101000000" != versions[0] || "20240102000000" != versions[1] {
+		test.Fatalf("migrations were expected to be applied in order, received %v instead", versions)
+	}
+
+	versions, upError = MigratorUp(migrator)
+	if upError != nil || 0 != len(versions) {
+		test.Fatalf("applied migrations were not expected to be applied again, received %v instead", versions)
+	}
+
+	versions, downError := MigratorDown(migrator, 1)
+	if downError != nil {
+		test.Fatal(downError)
+	}
+
+	if 1 != len(versions) || "20240102000000" != versions[0] {
+		test.Fatalf("last migration was expected to be reverted, received %v instead", versions)
+	}
+
+	if _, tableError := SqlTryExecute(sqlite, "INSERT INTO posts (title) VALUES (?)", "hello"); nil == tableError {
+		test.Fatal("posts table was expected to be dropped")
+	}
+
+	statuses, statusError := MigratorStatus(migrator)
+	if statusError != nil {
+		test.Fatal(statusError)
+	}
+
+	if 2 != len(statuses) || !statuses[0].Applied || statuses[1].Applied || "create_posts" != statuses[1].Name {
+		test.Fatalf("only the first migration was expected to be applied, received %v instead", statuses)
+	}
+
+	fileSystem["migrations/20240103000000_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE comments (text TEXT); INSERT INTO missing (id) VALUES (1)")}
+	versions, upError = MigratorUp(migrator)
+	if nil == upError {
+		test.Fatal("broken migration was expected to fail")
+	}
+
+	if 1 != len(versions) || "20240102000000" != versions[0] {
+		test.Fatalf("migrations before the broken one were expected to be applied, received %v instead", versions)
+	}
+
+	if _, tableError := SqlTryExecute(sqlite, "INSERT INTO comments (text) VALUES (?)", "hello"); nil == tableError {
+		test.Fatal("broken migration was expected to be rolled back")
+	}
+
+	if _, lockError := SqlTryExecute(sqlite, "INSERT INTO schema_migrations_lock (id) VALUES (1)"); lockError != nil {
+		test.Fatalf("migrations were expected to be unlocked, received %s instead", lockError)
+	}
+}
+
+func TestMigrationCreate(test *testing.T) {
+	directory := filepath.Join(test.TempDir(), "migrations")
+	up, down, createError := migrationCreate(directory, "create users")
+	if createError != nil {
+		test.Fatal(createError)
+	}
+
+	if !migrationFilePattern.MatchString(filepath.Base(up)) || !migrationFilePattern.MatchString(filepath.Base(down)) {
+		test.Fatalf("migration files were expected to be versioned, received %s and %s instead", up, down)
+	}
+
+	migrator := MigratorCreate(SqlCreate())
+	MigratorWithFileSystem(migrator, os.DirFS(directory), ".")
+	migrations, migrationsError := MigratorMigrations(migrator)
+	if migrationsError != nil {
+		test.Fatal(migrationsError)
+	}
+
+	if 1 != len(migrations) || "create_users" != migrations[0].Name {
+		test.Fatalf("created migration was expected to be found, received %v instead", migrations)
+	}
+}
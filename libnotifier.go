@@ -1,6 +1,8 @@
 package frizzante
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 )
@@ -8,6 +10,8 @@ import (
 type Notifier struct {
 	errorFile   *os.File
 	messageFile *os.File
+	accessFile  *os.File
+	requestId   string
 }
 
 // NotifierCreate creates a notifier.
@@ -15,12 +19,49 @@ func NotifierCreate() *Notifier {
 	return &Notifier{
 		errorFile:   os.Stderr,
 		messageFile: os.Stdout,
+		accessFile:  nil,
 	}
 }
 
+// NotifierWithAccessFile sets the file access entries are written to,
+// one json object per line.
+//
+// Access entries are discarded when the file is nil, which is the default.
+func NotifierWithAccessFile(self *Notifier, accessFile *os.File) {
+	self.accessFile = accessFile
+}
+
+// notifierWithRequestId creates a copy of the notifier which tags all errors with the given request id.
+func notifierWithRequestId(self *Notifier, requestId string) *Notifier {
+	notifier := *self
+	notifier.requestId = requestId
+	return &notifier
+}
+
+type requestIdContextKey struct{}
+
+// notifierWithContext creates a copy of the notifier which tags all errors with the id of the request ctx belongs to,
+// see ReceiveContext.
+//
+// It returns the notifier itself if ctx belongs to no request.
+func notifierWithContext(self *Notifier, ctx context.Context) *Notifier {
+	requestId, _ := ctx.Value(requestIdContextKey{}).(string)
+	if nil == self || "" == requestId {
+		return self
+	}
+	return notifierWithRequestId(self, requestId)
+}
+
 // NotifierSendError sends an error to the notifier.
+//
+// Errors sent while handling a request are prefixed with the id of said request.
 func NotifierSendError(self *Notifier, err error) {
-	_, errorLocal := self.errorFile.WriteString(err.Error() + "\n")
+	message := err.Error()
+	if "" != self.requestId {
+		message = fmt.Sprintf("[%s] %s", self.requestId, message)
+	}
+
+	_, errorLocal := self.errorFile.WriteString(message + "\n")
 	if errorLocal != nil {
 		fmt.Printf("notifier could not write to error file")
 	}
@@ -33,3 +74,21 @@ func NotifierSendMessage(self *Notifier, message string) {
 		fmt.Printf("notifier could not write to message file")
 	}
 }
+
+// NotifierSendAccess sends an access entry to the notifier.
+func NotifierSendAccess(self *Notifier, entry AccessEntry) {
+	if nil == self.accessFile {
+		return
+	}
+
+	entryBytes, marshalError := json.Marshal(entry)
+	if marshalError != nil {
+		NotifierSendError(self, marshalError)
+		return
+	}
+
+	_, errorLocal := self.accessFile.Write(append(entryBytes, '\n'))
+	if errorLocal != nil {
+		fmt.Printf("notifier could not write to access file")
+	}
+}
@@ -1,6 +1,7 @@
 package frizzante
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var pages = map[string]string{}
@@ -19,6 +21,7 @@ type Page struct {
 	efs        embed.FS
 	name       string
 	parameters map[string]string
+	request    *Request
 }
 
 // PageWithRender sets the page rendering mode.
@@ -38,10 +41,63 @@ type PageProps struct {
 	Data       map[string]any    `json:"data"`
 	Pages      map[string]string `json:"pages"`
 	Parameters map[string]string `json:"parameters"`
+	Csrf       string            `json:"csrf"`
+}
+
+// pageRender renders the page on the server and,
+// when the page is served as part of a request, records the duration of the render.
+func pageRender(self *Page, ctx context.Context, props string) (string, string, error) {
+	ctx, span := SpanStart(ctx, "render "+self.name)
+	defer SpanEnd(span)
+
+	startedAt := time.Now()
+	head, body, renderError := render(ctx, self.efs, props)
+	SpanWithError(span, renderError)
+	if nil != self.request {
+		MetricsHistogramObserve(self.request.server.metrics, "frizzante_render_duration_seconds", map[string]string{
+			"page": self.name,
+		}, time.Since(startedAt).Seconds())
+	}
+	return head, body, renderError
+}
+
+// pageCsrf finds the csrf token sent along with a page, empty when no route is protected.
+func pageCsrf(self *Page) string {
+	if nil == self.request || (!self.request.server.csrf && 0 == len(self.request.server.routeCsrf)) {
+		return ""
+	}
+	return ReceiveCsrfToken(self.request)
+}
+
+// pageFlash exposes the flash messages of the request to the page as its "flash" property,
+// unless the page already has such property.
+func pageFlash(self *Page) {
+	if nil == self.request {
+		return
+	}
+
+	if _, exists := self.data[flashSessionKey]; exists {
+		return
+	}
+
+	if flash := flashPage(self.request); len(flash) > 0 {
+		self.data[flashSessionKey] = flash
+	}
 }
 
 // PageCompile compiles a page.
+//
+// When the page is served as part of a request,
+// server side rendering stops as soon as the request context is done.
 func PageCompile(self *Page) (string, error) {
+	ctx := context.Background()
+	if nil != self.request {
+		ctx = ReceiveContext(self.request)
+	}
+
+	ctx, span := SpanStart(ctx, "compile "+self.name)
+	defer SpanEnd(span)
+
 	fileNameIndex := filepath.Join(".dist", "client", ".frizzante", "vite-project", "index.html")
 
 	var indexBytes []byte
@@ -60,11 +116,15 @@ func PageCompile(self *Page) (string, error) {
 		indexBytes = indexBytesLocal
 	}
 
+	csrf := pageCsrf(self)
+	pageFlash(self)
+
 	routerPropsBytes, jsonError := json.Marshal(PageProps{
 		Pages:      pages,
 		Page:       self.name,
 		Data:       self.data,
 		Parameters: self.parameters,
+		Csrf:       csrf,
 	})
 
 	if jsonError != nil {
@@ -79,7 +139,7 @@ func PageCompile(self *Page) (string, error) {
 	}
 
 	if RenderFull == self.render {
-		head, body, renderError := render(self.efs, routerPropsString)
+		head, body, renderError := pageRender(self, ctx, routerPropsString)
 		if renderError != nil {
 			return "", renderError
 		}
@@ -137,7 +197,7 @@ func PageCompile(self *Page) (string, error) {
 	}
 
 	if RenderServer == self.render {
-		head, body, renderError := render(self.efs, routerPropsString)
+		head, body, renderError := pageRender(self, ctx, routerPropsString)
 		if renderError != nil {
 			return "", renderError
 		}
@@ -165,7 +225,7 @@ func PageCompile(self *Page) (string, error) {
 	}
 
 	if RenderHeadless == self.render {
-		_, body, renderError := render(self.efs, routerPropsString)
+		_, body, renderError := pageRender(self, ctx, routerPropsString)
 
 		if renderError != nil {
 			return "", renderError
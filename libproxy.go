@@ -0,0 +1,213 @@
+package frizzante
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+type forwardedElement struct {
+	client string
+	scheme string
+	host   string
+}
+
+// ServerWithTrustedProxies sets the proxies allowed to forward requests to the server,
+// as ip addresses or cidr ranges, like "10.0.0.0/8".
+//
+// Forwarded and X-Forwarded-* headers are honored only when they're set by trusted proxies,
+// see ReceiveClientIp, ReceiveScheme and ReceiveHost.
+func ServerWithTrustedProxies(self *Server, cidrs []string) {
+	self.trustedProxies = []*net.IPNet{}
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if nil == ip {
+				NotifierSendError(self.notifier, fmt.Errorf("trusted proxy `%s` is not a valid ip address", cidr))
+				continue
+			}
+
+			bits := 128
+			if nil != ip.To4() {
+				ip = ip.To4()
+				bits = 32
+			}
+			self.trustedProxies = append(self.trustedProxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, parseError := net.ParseCIDR(cidr)
+		if parseError != nil {
+			NotifierSendError(self.notifier, parseError)
+			continue
+		}
+		self.trustedProxies = append(self.trustedProxies, network)
+	}
+}
+
+// serverTrustsProxy checks if the given address belongs to a trusted proxy.
+func serverTrustsProxy(self *Server, address string) bool {
+	ip := net.ParseIP(address)
+	if nil == ip {
+		return false
+	}
+
+	for _, network := range self.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardedAddress strips quotes, brackets and ports from a forwarded node, like `"[2001:db8::1]:4711"`.
+func forwardedAddress(node string) string {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	host, _, splitError := net.SplitHostPort(node)
+	if nil == splitError {
+		return host
+	}
+	return strings.Trim(node, "[]")
+}
+
+// forwardedSplit splits a comma separated header, possibly repeated, into its values.
+func forwardedSplit(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if "" != strings.TrimSpace(part) {
+				result = append(result, strings.TrimSpace(part))
+			}
+		}
+	}
+	return result
+}
+
+// forwardedParse parses the Forwarded header or, when missing, the X-Forwarded-* headers,
+// into one element per hop, from the client to the closest proxy.
+func forwardedParse(request *Request) []forwardedElement {
+	header := request.httpRequest.Header
+	var elements []forwardedElement
+
+	forwarded := forwardedSplit(header.Values("Forwarded"))
+	if len(forwarded) > 0 {
+		for _, part := range forwarded {
+			var element forwardedElement
+			for _, pair := range strings.Split(part, ";") {
+				key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if !found {
+					continue
+				}
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+				switch strings.ToLower(key) {
+				case "for":
+					element.client = forwardedAddress(value)
+				case "proto":
+					element.scheme = strings.ToLower(value)
+				case "host":
+					element.host = value
+				}
+			}
+			elements = append(elements, element)
+		}
+		return elements
+	}
+
+	clients := forwardedSplit(header.Values("X-Forwarded-For"))
+	schemes := forwardedSplit(header.Values("X-Forwarded-Proto"))
+	hosts := forwardedSplit(header.Values("X-Forwarded-Host"))
+	for index, client := range clients {
+		element := forwardedElement{client: forwardedAddress(client)}
+
+		// Proxies usually set a single scheme and host, in which case the values set
+		// by the closest proxy, which are the last ones, apply to all hops.
+		if len(schemes) == len(clients) {
+			element.scheme = strings.ToLower(schemes[index])
+		} else if len(schemes) > 0 {
+			element.scheme = strings.ToLower(schemes[len(schemes)-1])
+		}
+
+		if len(hosts) == len(clients) {
+			element.host = hosts[index]
+		} else if len(hosts) > 0 {
+			element.host = hosts[len(hosts)-1]
+		}
+
+		elements = append(elements, element)
+	}
+
+	return elements
+}
+
+// requestForwarded resolves the client, scheme and host of the request.
+//
+// Hops are walked from the closest proxy towards the client, stopping at the first untrusted one,
+// which is the client, because untrusted hops can forge anything before them.
+func requestForwarded(self *Request) forwardedElement {
+	if nil != self.forwarded {
+		return *self.forwarded
+	}
+
+	remote := forwardedAddress(self.httpRequest.RemoteAddr)
+	resolved := forwardedElement{
+		client: remote,
+		scheme: "http",
+		host:   self.httpRequest.Host,
+	}
+
+	if nil != self.httpRequest.TLS {
+		resolved.scheme = "https"
+	}
+
+	if serverTrustsProxy(self.server, remote) {
+		elements := forwardedParse(self)
+		for index := len(elements) - 1; index >= 0; index-- {
+			element := elements[index]
+			if "" == element.client {
+				break
+			}
+
+			resolved.client = element.client
+			if "http" == element.scheme || "https" == element.scheme {
+				resolved.scheme = element.scheme
+			}
+			if "" != element.host {
+				resolved.host = element.host
+			}
+
+			if !serverTrustsProxy(self.server, element.client) {
+				break
+			}
+		}
+	}
+
+	self.forwarded = &resolved
+	return resolved
+}
+
+// ReceiveClientIp gets the ip address of the client.
+//
+// Behind trusted proxies, it is read from the Forwarded or X-Forwarded-For header.
+func ReceiveClientIp(self *Request) string {
+	return requestForwarded(self).client
+}
+
+// ReceiveScheme gets the scheme the client used to send the request, either "http" or "https".
+//
+// Behind trusted proxies, it is read from the Forwarded or X-Forwarded-Proto header.
+func ReceiveScheme(self *Request) string {
+	return requestForwarded(self).scheme
+}
+
+// ReceiveHost gets the host the client sent the request to, including the port, if any.
+//
+// Behind trusted proxies, it is read from the Forwarded or X-Forwarded-Host header.
+func ReceiveHost(self *Request) string {
+	return requestForwarded(self).host
+}
+
+// requestIsForwarded checks if the request has been forwarded by a trusted proxy.
+func requestIsForwarded(self *Request) bool {
+	return serverTrustsProxy(self.server, forwardedAddress(self.httpRequest.RemoteAddr))
+}
@@ -0,0 +1,127 @@
+package frizzante
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestForwarded(test *testing.T) {
+	server := ServerCreate()
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithTrustedProxies(server, []string{"10.0.0.0/8", "192.168.1.1"})
+
+	type scenario struct {
+		remote   string
+		header   map[string]string
+		client   string
+		scheme   string
+		host     string
+		scenario string
+	}
+
+	scenarios := []scenario{
+		{
+			scenario: "untrusted remote",
+			remote:   "203.0.113.7:5000",
+			header:   map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Forwarded-Proto": "https"},
+			client:   "203.0.113.7",
+			scheme:   "http",
+			host:     "example.com",
+		},
+		{
+			scenario: "trusted chain",
+			remote:   "192.168.1.1:5000",
+			header: map[string]string{
+				"X-Forwarded-For":   "6.6.6.6, 198.51.100.4, 10.1.2.3",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "shop.example.com",
+			},
+			client: "198.51.100.4",
+			scheme: "https",
+			host:   "shop.example.com",
+		},
+		{
+			scenario: "forwarded header",
+			remote:   "10.0.0.1:5000",
+			header:   map[string]string{"Forwarded": `for="[2001:db8::1]:4711";proto=https;host=shop.example.com, for=10.0.0.2`},
+			client:   "2001:db8::1",
+			scheme:   "https",
+			host:     "shop.example.com",
+		},
+	}
+
+	for _, current := range scenarios {
+		httpRequest := httptest.NewRequest("GET", "http://example.com/", nil)
+		httpRequest.RemoteAddr = current.remote
+		for key, value := range current.header {
+			httpRequest.Header.Set(key, value)
+		}
+		request := &Request{server: server, httpRequest: httpRequest}
+
+		if current.client != ReceiveClientIp(request) {
+			test.Fatalf("%s was expected to have client ip '%s', received '%s' instead", current.scenario, current.client, ReceiveClientIp(request))
+		}
+		if current.scheme != ReceiveScheme(request) {
+			test.Fatalf("%s was expected to have scheme '%s', received '%s' instead", current.scenario, current.scheme, ReceiveScheme(request))
+		}
+		if current.host != ReceiveHost(request) {
+			test.Fatalf("%s was expected to have host '%s', received '%s' instead", current.scenario, current.host, ReceiveHost(request))
+		}
+	}
+}
+
+func TestSendRedirectToSecure(test *testing.T) {
+	server := ServerCreate()
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithSecurePort(server, 8443)
+	ServerWithTrustedProxies(server, []string{"10.0.0.1"})
+
+	redirect := func(remote string, host string, header map[string]string) (bool, string) {
+		httpRequest := httptest.NewRequest("GET", "http://"+host+"/cart?id=1", nil)
+		httpRequest.RemoteAddr = remote
+		for key, value := range header {
+			httpRequest.Header.Set(key, value)
+		}
+		recorder := httptest.NewRecorder()
+		var redirected bool
+		serverHandle(server, "", recorder, httpRequest, func(_ *Request, response *Response) {
+			redirected = SendRedirectToSecure(response, 301)
+			SendEcho(response, "")
+		})
+		return redirected, recorder.Header().Get("Location")
+	}
+
+	redirected, _ := redirect("203.0.113.7:5000", "example.com:8080", nil)
+	if redirected {
+		test.Fatal("request was not expected to be redirected without a certificate")
+	}
+
+	ServerWithCertificateAndKey(server, "certificate.pem", "key.pem")
+
+	redirected, location := redirect("203.0.113.7:5000", "example.com:8080", nil)
+	if !redirected || "https://example.com:8443/cart?id=1" != location {
+		test.Fatalf("request was expected to be redirected to the secure port, received '%s' instead", location)
+	}
+
+	redirected, location = redirect("203.0.113.7:5000", "example.com", nil)
+	if !redirected || "https://example.com:8443/cart?id=1" != location {
+		test.Fatalf("request without port was expected to be redirected to the secure port, received '%s' instead", location)
+	}
+
+	redirected, location = redirect("10.0.0.1:5000", "internal:8080", map[string]string{
+		"X-Forwarded-For":   "198.51.100.4",
+		"X-Forwarded-Proto": "http",
+		"X-Forwarded-Host":  "shop.example.com",
+	})
+	if !redirected || "https://shop.example.com/cart?id=1" != location {
+		test.Fatalf("proxied request was expected to be redirected to the default https port, received '%s' instead", location)
+	}
+
+	redirected, _ = redirect("10.0.0.1:5000", "internal:8080", map[string]string{
+		"X-Forwarded-For":   "198.51.100.4",
+		"X-Forwarded-Proto": "https",
+	})
+	if redirected {
+		test.Fatal("request secured by the proxy was not expected to be redirected")
+	}
+}
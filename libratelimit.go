@@ -0,0 +1,296 @@
+package frizzante
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type RateLimitAlgorithm int64
+
+const (
+	RateLimitTokenBucket   RateLimitAlgorithm = 0 // Allows bursts up to the limit, then refills at a steady rate.
+	RateLimitSlidingWindow RateLimitAlgorithm = 1 // Allows up to the limit within any window, weighting the previous window.
+)
+
+// RateLimitState is the state of a key, persisted by a RateLimitStore.
+type RateLimitState struct {
+	Tokens        float64   `json:"tokens"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	WindowStart   time.Time `json:"window_start"`
+	PreviousCount int64     `json:"previous_count"`
+	CurrentCount  int64     `json:"current_count"`
+}
+
+// RateLimitStore loads the state of key, invokes update and saves the updated state,
+// all atomically, so that concurrent requests with the same key never observe the same state.
+//
+// States that are not updated for longer than expiry can be discarded.
+type RateLimitStore = func(key string, expiry time.Duration, update func(state *RateLimitState))
+
+type RateLimiter struct {
+	name      string
+	algorithm RateLimitAlgorithm
+	limit     int64
+	window    time.Duration
+	store     RateLimitStore
+	key       func(request *Request) string
+}
+
+type rateLimitMemoryEntry struct {
+	state     RateLimitState
+	expiresAt time.Time
+}
+
+// RateLimitMemoryStoreCreate creates a store that keeps states in memory.
+//
+// Memory stores are not shared between processes.
+func RateLimitMemoryStoreCreate() RateLimitStore {
+	var lock sync.Mutex
+	entries := map[string]*rateLimitMemoryEntry{}
+	sweptAt := time.Now()
+
+	return func(key string, expiry time.Duration, update func(state *RateLimitState)) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		now := time.Now()
+		if now.Sub(sweptAt) > time.Minute {
+			sweptAt = now
+			for entryKey, entry := range entries {
+				if now.After(entry.expiresAt) {
+					delete(entries, entryKey)
+				}
+			}
+		}
+
+		entry, exists := entries[key]
+		if !exists || now.After(entry.expiresAt) {
+			entry = &rateLimitMemoryEntry{}
+			entries[key] = entry
+		}
+
+		update(&entry.state)
+		entry.expiresAt = now.Add(expiry)
+	}
+}
+
+// RateLimitKeyIp identifies clients by ip address, see ReceiveClientIp.
+func RateLimitKeyIp(request *Request) string {
+	return ReceiveClientIp(request)
+}
+
+// RateLimitKeySessionId identifies clients by session id,
+// falling back to the ip address when the client has no session.
+//
+// Clients can drop their session cookie at any time,
+// so this is mostly useful for routes that require a valid session.
+func RateLimitKeySessionId(request *Request) string {
+	if "" != request.sessionId {
+		return "session:" + request.sessionId
+	}
+
+	sessionId, found := request.server.sessionStore.find(request)
+	if found {
+		return "session:" + sessionId
+	}
+
+	return "ip:" + RateLimitKeyIp(request)
+}
+
+// RateLimitKeyHeader identifies clients by the value of a header, like an api key.
+//
+// Requests without the header are identified by ip address instead, see RateLimitKeyIp,
+// so that clients can't bypass the limit by omitting the header.
+func RateLimitKeyHeader(name string) func(request *Request) string {
+	return func(request *Request) string {
+		value := ReceiveHeader(request, name)
+		if "" == value {
+			return "ip:" + RateLimitKeyIp(request)
+		}
+		return "header:" + value
+	}
+}
+
+// RateLimiterCreate creates a rate limiter that allows limit requests per window.
+//
+// By default, rate limiters use the token bucket algorithm, identify clients by ip address
+// and keep their state in memory.
+//
+// It returns an error if limit or window are not positive.
+func RateLimiterCreate(limit int64, window time.Duration) (*RateLimiter, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("rate limit must be positive, received %d instead", limit)
+	}
+
+	if window <= 0 {
+		return nil, fmt.Errorf("rate limit window must be positive, received %s instead", window)
+	}
+
+	return &RateLimiter{
+		name:      "rate-limit",
+		algorithm: RateLimitTokenBucket,
+		limit:     limit,
+		window:    window,
+		store:     RateLimitMemoryStoreCreate(),
+		key:       RateLimitKeyIp,
+	}, nil
+}
+
+// RateLimiterWithName sets the name of the rate limiter,
+// which prefixes all keys, so that rate limiters can share the same store.
+func RateLimiterWithName(self *RateLimiter, name string) {
+	self.name = name
+}
+
+// RateLimiterWithAlgorithm sets the algorithm of the rate limiter.
+func RateLimiterWithAlgorithm(self *RateLimiter, algorithm RateLimitAlgorithm) {
+	self.algorithm = algorithm
+}
+
+// RateLimiterWithStore sets the store of the rate limiter.
+func RateLimiterWithStore(self *RateLimiter, store RateLimitStore) {
+	self.store = store
+}
+
+// RateLimiterWithKey sets the function identifying clients.
+//
+// Requests for which key returns an empty string are not limited.
+func RateLimiterWithKey(self *RateLimiter, key func(request *Request) string) {
+	self.key = key
+}
+
+// rateLimitTokenBucket takes a token from the bucket.
+func rateLimitTokenBucket(self *RateLimiter, state *RateLimitState, now time.Time) (allowed bool, remaining int64, reset time.Duration, retryAfter time.Duration) {
+	limit := float64(self.limit)
+	rate := limit / self.window.Seconds()
+
+	if state.UpdatedAt.IsZero() {
+		state.Tokens = limit
+	} else {
+		elapsed := now.Sub(state.UpdatedAt).Seconds()
+		state.Tokens = math.Min(limit, state.Tokens+math.Max(0, elapsed)*rate)
+	}
+	state.UpdatedAt = now
+
+	if state.Tokens >= 1 {
+		state.Tokens--
+		allowed = true
+	} else {
+		retryAfter = time.Duration((1 - state.Tokens) / rate * float64(time.Second))
+	}
+
+	remaining = int64(math.Floor(state.Tokens))
+	reset = time.Duration((limit - state.Tokens) / rate * float64(time.Second))
+	return
+}
+
+// rateLimitSlidingWindow counts the request in the current window,
+// estimating the count of the sliding window by weighting the count of the previous window.
+func rateLimitSlidingWindow(self *RateLimiter, state *RateLimitState, now time.Time) (allowed bool, remaining int64, reset time.Duration, retryAfter time.Duration) {
+	windowStart := now.Truncate(self.window)
+	if !state.WindowStart.Equal(windowStart) {
+		if state.WindowStart.Equal(windowStart.Add(-self.window)) {
+			state.PreviousCount = state.CurrentCount
+		} else {
+			state.PreviousCount = 0
+		}
+		state.CurrentCount = 0
+		state.WindowStart = windowStart
+	}
+
+	weight := 1 - float64(now.Sub(windowStart))/float64(self.window)
+	estimate := float64(state.PreviousCount)*weight + float64(state.CurrentCount)
+	reset = windowStart.Add(self.window).Sub(now)
+
+	if estimate+1 <= float64(self.limit) {
+		state.CurrentCount++
+		estimate++
+		allowed = true
+	} else if state.CurrentCount >= self.limit || 0 == state.PreviousCount {
+		retryAfter = reset
+	} else {
+		// Time until the weight of the previous window leaves room for one more request.
+		room := float64(self.limit-1-state.CurrentCount) / float64(state.PreviousCount)
+		retryAfter = time.Duration((1-room)*float64(self.window)) - now.Sub(windowStart)
+		retryAfter = max(0, min(retryAfter, reset))
+	}
+
+	remaining = max(0, int64(math.Floor(float64(self.limit)-estimate)))
+	return
+}
+
+// RateLimiterTake counts a request against the limit of its client.
+//
+// It returns false if the client exceeded the limit, along with the time the client should wait before retrying.
+func RateLimiterTake(self *RateLimiter, request *Request) (allowed bool, remaining int64, reset time.Duration, retryAfter time.Duration) {
+	key := self.key(request)
+	if "" == key {
+		return true, self.limit, 0, 0
+	}
+
+	return rateLimiterTakeKey(self, key)
+}
+
+func rateLimiterTakeKey(self *RateLimiter, key string) (allowed bool, remaining int64, reset time.Duration, retryAfter time.Duration) {
+	self.store(self.name+":"+key, 2*self.window, func(state *RateLimitState) {
+		now := time.Now()
+		if RateLimitSlidingWindow == self.algorithm {
+			allowed, remaining, reset, retryAfter = rateLimitSlidingWindow(self, state, now)
+			return
+		}
+		allowed, remaining, reset, retryAfter = rateLimitTokenBucket(self, state, now)
+	})
+
+	return
+}
+
+// rateLimitSeconds rounds a duration up to whole seconds.
+func rateLimitSeconds(duration time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(duration.Seconds())), 10)
+}
+
+// rateLimiterVerify counts the request and sends the RateLimit headers.
+//
+// If the client exceeded the limit, it also sends the Retry-After header and status 429 Too Many Requests.
+func rateLimiterVerify(self *RateLimiter, request *Request, response *Response) bool {
+	key := self.key(request)
+	if "" == key {
+		return true
+	}
+
+	allowed, remaining, reset, retryAfter := rateLimiterTakeKey(self, key)
+
+	SendHeader(response, "RateLimit-Limit", strconv.FormatInt(self.limit, 10))
+	SendHeader(response, "RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+	SendHeader(response, "RateLimit-Reset", rateLimitSeconds(reset))
+
+	if !allowed {
+		SendHeader(response, "Retry-After", rateLimitSeconds(retryAfter))
+		SendTooManyRequests(response)
+		SendEcho(response, "Too Many Requests")
+		return false
+	}
+
+	return true
+}
+
+// RateLimiterApiGuard creates an api guard that rejects clients exceeding the limit.
+func RateLimiterApiGuard(self *RateLimiter) ApiGuardFunction {
+	return func(request *Request, response *Response, pass func()) {
+		if rateLimiterVerify(self, request, response) {
+			pass()
+		}
+	}
+}
+
+// RateLimiterIndexGuard creates an index guard that rejects clients exceeding the limit.
+func RateLimiterIndexGuard(self *RateLimiter) IndexGuard {
+	return func(request *Request, response *Response, _ *Page, pass func()) {
+		if rateLimiterVerify(self, request, response) {
+			pass()
+		}
+	}
+}
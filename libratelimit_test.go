@@ -0,0 +1,149 @@
+package frizzante
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterCreate(test *testing.T) {
+	for _, parameters := range []struct {
+		limit  int64
+		window time.Duration
+	}{{0, time.Minute}, {-1, time.Minute}, {1, 0}, {1, -time.Second}} {
+		_, limiterError := RateLimiterCreate(parameters.limit, parameters.window)
+		if nil == limiterError {
+			test.Fatalf("rate limiter with limit %d and window %s was expected to be rejected", parameters.limit, parameters.window)
+		}
+	}
+}
+
+func TestRateLimitTokenBucket(test *testing.T) {
+	limiter, limiterError := RateLimiterCreate(2, 2*time.Second)
+	if limiterError != nil {
+		test.Fatal(limiterError)
+	}
+	state := &RateLimitState{}
+	now := time.Now()
+
+	for index := 0; index < 2; index++ {
+		allowed, _, _, _ := rateLimitTokenBucket(limiter, state, now)
+		if !allowed {
+			test.Fatalf("request %d was expected to be allowed", index)
+		}
+	}
+
+	allowed, remaining, _, retryAfter := rateLimitTokenBucket(limiter, state, now)
+	if allowed || 0 != remaining {
+		test.Fatal("request was expected to be rejected once the bucket is empty")
+	}
+	if time.Second != retryAfter {
+		test.Fatalf("client was expected to retry after 1s, received %s instead", retryAfter)
+	}
+
+	allowed, _, _, _ = rateLimitTokenBucket(limiter, state, now.Add(time.Second))
+	if !allowed {
+		test.Fatal("request was expected to be allowed once a token is refilled")
+	}
+}
+
+func TestRateLimitSlidingWindow(test *testing.T) {
+	limiter, limiterError := RateLimiterCreate(4, time.Minute)
+	if limiterError != nil {
+		test.Fatal(limiterError)
+	}
+	state := &RateLimitState{}
+	windowStart := time.Now().Truncate(time.Minute)
+
+	for index := 0; index < 4; index++ {
+		allowed, _, _, _ := rateLimitSlidingWindow(limiter, state, windowStart.Add(50*time.Second))
+		if !allowed {
+			test.Fatalf("request %d was expected to be allowed", index)
+		}
+	}
+
+	allowed, _, _, _ := rateLimitSlidingWindow(limiter, state, windowStart.Add(59*time.Second))
+	if allowed {
+		test.Fatal("request was expected to be rejected once the window is full")
+	}
+
+	// A quarter into the next window, the previous window still weights 3 requests.
+	allowed, remaining, _, _ := rateLimitSlidingWindow(limiter, state, windowStart.Add(75*time.Second))
+	if !allowed || 0 != remaining {
+		test.Fatalf("request was expected to be allowed with 0 remaining, received allowed '%t' and remaining %d instead", allowed, remaining)
+	}
+
+	allowed, _, _, retryAfter := rateLimitSlidingWindow(limiter, state, windowStart.Add(75*time.Second))
+	if allowed || 15*time.Second != retryAfter {
+		test.Fatalf("request was expected to be rejected with retry after 15s, received allowed '%t' and retry after %s instead", allowed, retryAfter)
+	}
+}
+
+func TestRateLimiterApiGuard(test *testing.T) {
+	limiter, limiterError := RateLimiterCreate(1, time.Minute)
+	if limiterError != nil {
+		test.Fatal(limiterError)
+	}
+	RateLimiterWithKey(limiter, RateLimitKeyHeader("X-Api-Key"))
+
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithApiGuard(server, RateLimiterApiGuard(limiter))
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /limited")
+		serve(func(_ *Request, response *Response) {
+			SendEcho(response, "ok")
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	send := func(key string) *http.Response {
+		request, requestError := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/limited", port), nil)
+		if requestError != nil {
+			test.Fatal(requestError)
+		}
+		if "" != key {
+			request.Header.Set("X-Api-Key", key)
+		}
+		response, doError := http.DefaultClient.Do(request)
+		if doError != nil {
+			test.Fatal(doError)
+		}
+		_ = response.Body.Close()
+		return response
+	}
+
+	first := send("a")
+	if 200 != first.StatusCode || "1" != first.Header.Get("RateLimit-Limit") || "0" != first.Header.Get("RateLimit-Remaining") {
+		test.Fatalf("first request was expected to be allowed, received status %d and header %v instead", first.StatusCode, first.Header)
+	}
+
+	second := send("a")
+	if 429 != second.StatusCode || "60" != second.Header.Get("Retry-After") {
+		test.Fatalf("second request was expected to be rejected, received status %d and header %v instead", second.StatusCode, second.Header)
+	}
+
+	other := send("b")
+	if 200 != other.StatusCode {
+		test.Fatalf("request with a different key was expected to be allowed, received status %d instead", other.StatusCode)
+	}
+
+	anonymous := send("")
+	if 200 != anonymous.StatusCode || "1" != anonymous.Header.Get("RateLimit-Limit") {
+		test.Fatalf("request without key was expected to be limited by ip address, received status %d instead", anonymous.StatusCode)
+	}
+
+	anonymous = send("")
+	if 429 != anonymous.StatusCode {
+		test.Fatalf("second request without key was expected to be rejected, received status %d instead", anonymous.StatusCode)
+	}
+}
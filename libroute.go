@@ -0,0 +1,403 @@
+package frizzante
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+type routeSegmentKind int64
+
+const (
+	routeSegmentLiteral  routeSegmentKind = 0 // Matches exactly one segment with the same value.
+	routeSegmentWildcard routeSegmentKind = 1 // Matches exactly one non-empty segment, like `{id}`.
+	routeSegmentMulti    routeSegmentKind = 2 // Matches the rest of the path, like `{path...}` or a trailing slash.
+)
+
+type routeSegment struct {
+	kind  routeSegmentKind
+	value string
+}
+
+type routeRelation int64
+
+const (
+	routeRelationDisjoint    routeRelation = 0 // No request matches both patterns.
+	routeRelationEquivalent  routeRelation = 1 // Both patterns match the same requests.
+	routeRelationMoreGeneral routeRelation = 2 // The first pattern matches a superset of the second.
+	routeRelationMoreSpecial routeRelation = 3 // The first pattern matches a subset of the second.
+	routeRelationOverlaps    routeRelation = 4 // Some requests match both patterns, but neither takes precedence.
+)
+
+type RouteInfo struct {
+	Method  string   // Method of the pattern, empty when the pattern matches any method.
+	Host    string   // Host of the pattern, empty when the pattern matches any host.
+	Path    string   // Path of the pattern.
+	Pattern string   // The pattern as it was registered.
+	Page    string   // Page served by the route, empty for apis.
+	Handler string   // Source location of the serve, show or action function.
+	Guards  []string // Names of the guards executed before the handler.
+}
+
+// routeParsePattern splits a pattern into its method, host and path,
+// following the syntax of http.ServeMux, `[METHOD ][HOST]/[PATH]`.
+func routeParsePattern(pattern string) (method string, host string, path string) {
+	rest := strings.TrimSpace(pattern)
+	index := strings.IndexAny(rest, " \t")
+	if index >= 0 {
+		method = rest[:index]
+		rest = strings.TrimLeft(rest[index:], " \t")
+	}
+
+	slash := strings.Index(rest, "/")
+	if slash < 0 {
+		host = rest
+		return
+	}
+
+	host = rest[:slash]
+	path = rest[slash:]
+	return
+}
+
+// routeParseSegments parses the path of a pattern into segments.
+func routeParseSegments(path string) []routeSegment {
+	var segments []routeSegment
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	last := len(parts) - 1
+
+	for index, part := range parts {
+		if index == last && "" == part {
+			segments = append(segments, routeSegment{kind: routeSegmentMulti})
+			continue
+		}
+
+		if index == last && "{$}" == part {
+			segments = append(segments, routeSegment{kind: routeSegmentLiteral, value: ""})
+			continue
+		}
+
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "...}") {
+			segments = append(segments, routeSegment{kind: routeSegmentMulti, value: part[1 : len(part)-4]})
+			continue
+		}
+
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			segments = append(segments, routeSegment{kind: routeSegmentWildcard, value: part[1 : len(part)-1]})
+			continue
+		}
+
+		segments = append(segments, routeSegment{kind: routeSegmentLiteral, value: part})
+	}
+
+	return segments
+}
+
+// routeMatchesPath checks if the segments of a route match the given request path.
+func routeMatchesPath(segments []routeSegment, path string) bool {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for index, segment := range segments {
+		if routeSegmentMulti == segment.kind {
+			return index < len(parts)
+		}
+
+		if index >= len(parts) {
+			return false
+		}
+
+		if routeSegmentWildcard == segment.kind {
+			if "" == parts[index] {
+				return false
+			}
+			continue
+		}
+
+		if parts[index] != segment.value {
+			return false
+		}
+	}
+
+	return len(parts) == len(segments)
+}
+
+// routeMatchesPrefixOnly checks if the route matches the given request path
+// only because its pattern ends with a trailing slash, which http.ServeMux treats as a prefix.
+func routeMatchesPrefixOnly(route *Route, path string) bool {
+	count := len(route.segments)
+	if 0 == count {
+		return false
+	}
+
+	last := route.segments[count-1]
+	return routeSegmentMulti == last.kind && "" == last.value && path != route.path
+}
+
+// routeMatchesHost checks if the host of a route matches the given request host.
+func routeMatchesHost(host string, requestHost string) bool {
+	if "" == host {
+		return true
+	}
+
+	if index := strings.LastIndex(requestHost, ":"); index >= 0 && !strings.Contains(requestHost[index:], "]") {
+		requestHost = requestHost[:index]
+	}
+
+	return host == requestHost
+}
+
+// routeMatchesMethod checks if the method of a route matches the given request method.
+func routeMatchesMethod(method string, requestMethod string) bool {
+	return "" == method || method == requestMethod || ("GET" == method && "HEAD" == requestMethod)
+}
+
+func routeRelationCombine(left routeRelation, right routeRelation) routeRelation {
+	if routeRelationDisjoint == left || routeRelationDisjoint == right {
+		return routeRelationDisjoint
+	}
+
+	if routeRelationEquivalent == left {
+		return right
+	}
+
+	if routeRelationEquivalent == right || left == right {
+		return left
+	}
+
+	return routeRelationOverlaps
+}
+
+func routeRelationInverse(relation routeRelation) routeRelation {
+	if routeRelationMoreGeneral == relation {
+		return routeRelationMoreSpecial
+	}
+
+	if routeRelationMoreSpecial == relation {
+		return routeRelationMoreGeneral
+	}
+
+	return relation
+}
+
+func routeCompareMethods(left string, right string) routeRelation {
+	if left == right {
+		return routeRelationEquivalent
+	}
+
+	if "" == left || ("GET" == left && "HEAD" == right) {
+		return routeRelationMoreGeneral
+	}
+
+	if "" == right || ("HEAD" == left && "GET" == right) {
+		return routeRelationMoreSpecial
+	}
+
+	return routeRelationDisjoint
+}
+
+func routeCompareSegment(left routeSegment, right routeSegment) routeRelation {
+	if routeSegmentLiteral == left.kind && routeSegmentLiteral == right.kind {
+		if left.value == right.value {
+			return routeRelationEquivalent
+		}
+		return routeRelationDisjoint
+	}
+
+	if routeSegmentWildcard == left.kind && routeSegmentWildcard == right.kind {
+		return routeRelationEquivalent
+	}
+
+	if routeSegmentLiteral == left.kind {
+		// An empty literal only matches the end of a path, which wildcards never do.
+		if "" == left.value {
+			return routeRelationDisjoint
+		}
+		return routeRelationMoreSpecial
+	}
+
+	return routeRelationInverse(routeCompareSegment(right, left))
+}
+
+func routeCompareSegments(left []routeSegment, right []routeSegment) routeRelation {
+	relation := routeRelationEquivalent
+	for index := 0; ; index++ {
+		leftDone := index >= len(left)
+		rightDone := index >= len(right)
+
+		if leftDone && rightDone {
+			return relation
+		}
+
+		if leftDone || rightDone {
+			return routeRelationDisjoint
+		}
+
+		leftMulti := routeSegmentMulti == left[index].kind
+		rightMulti := routeSegmentMulti == right[index].kind
+
+		if leftMulti && rightMulti {
+			return relation
+		}
+
+		if leftMulti {
+			return routeRelationCombine(relation, routeRelationMoreGeneral)
+		}
+
+		if rightMulti {
+			return routeRelationCombine(relation, routeRelationMoreSpecial)
+		}
+
+		relation = routeRelationCombine(relation, routeCompareSegment(left[index], right[index]))
+		if routeRelationDisjoint == relation {
+			return relation
+		}
+	}
+}
+
+// routeCompare computes the relationship between two routes,
+// using the same precedence rules as http.ServeMux.
+func routeCompare(left *Route, right *Route) routeRelation {
+	if left.host != right.host {
+		// Either one of the two hosts is empty, in which case the other one takes precedence,
+		// or the hosts are different, in which case they never match the same requests.
+		return routeRelationDisjoint
+	}
+
+	relation := routeCompareMethods(left.method, right.method)
+	if routeRelationDisjoint == relation {
+		return relation
+	}
+
+	return routeRelationCombine(relation, routeCompareSegments(left.segments, right.segments))
+}
+
+// routeVerifyConflicts checks if the route conflicts with, or is shadowed by, any registered route.
+//
+// This detects patterns http.ServeMux would otherwise panic on.
+func routeVerifyConflicts(self *Server, route *Route) error {
+	for _, registered := range self.routes {
+		relation := routeCompare(route, registered)
+		if routeRelationEquivalent == relation {
+			return fmt.Errorf("pattern `%s` is shadowed by pattern `%s`, which matches the same requests", route.pattern, registered.pattern)
+		}
+
+		if routeRelationOverlaps == relation {
+			return fmt.Errorf("pattern `%s` conflicts with pattern `%s`, both match some of the same requests and neither is more specific", route.pattern, registered.pattern)
+		}
+	}
+
+	return nil
+}
+
+// routeFunctionName gets the fully qualified name of a function.
+func routeFunctionName(function any) string {
+	value := reflect.ValueOf(function)
+	if reflect.Func != value.Kind() || value.IsNil() {
+		return ""
+	}
+
+	runtimeFunction := runtime.FuncForPC(value.Pointer())
+	if nil == runtimeFunction {
+		return ""
+	}
+
+	return runtimeFunction.Name()
+}
+
+// routeFunctionLocation gets the source location at which a function is declared.
+func routeFunctionLocation(function any) string {
+	value := reflect.ValueOf(function)
+	if reflect.Func != value.Kind() || value.IsNil() {
+		return ""
+	}
+
+	runtimeFunction := runtime.FuncForPC(value.Pointer())
+	if nil == runtimeFunction {
+		return ""
+	}
+
+	fileName, line := runtimeFunction.FileLine(runtimeFunction.Entry())
+	return fmt.Sprintf("%s:%d", fileName, line)
+}
+
+// routeAllowedMethods lists the methods of all routes matching the host and path of the request.
+//
+// Routes registered with a GET method also allow the HEAD method.
+//
+// Routes matching the path only as a prefix, like "GET /", and the not found route
+// are ignored, so that unknown paths are not found regardless of the method.
+func routeAllowedMethods(self *Server, httpRequest *http.Request) []string {
+	notFound := routeFunctionName(notFoundServe)
+	var methods []string
+	for _, route := range self.routes {
+		if "" == route.method {
+			continue
+		}
+
+		if routeMatchesPrefixOnly(route, httpRequest.URL.Path) || notFound == routeFunctionName(route.handler) {
+			continue
+		}
+
+		if !routeMatchesHost(route.host, httpRequest.Host) || !routeMatchesPath(route.segments, httpRequest.URL.Path) {
+			continue
+		}
+
+		if !slices.Contains(methods, route.method) {
+			methods = append(methods, route.method)
+		}
+
+		if "GET" == route.method && !slices.Contains(methods, "HEAD") {
+			methods = append(methods, "HEAD")
+		}
+	}
+
+	slices.Sort(methods)
+	return methods
+}
+
+// routeMatch finds the route http.ServeMux would dispatch a request with the given method, host and path to.
+func routeMatch(self *Server, method string, requestHost string, path string) *Route {
+	var matched *Route
+	for _, route := range self.routes {
+		if !routeMatchesMethod(route.method, method) || !routeMatchesHost(route.host, requestHost) || !routeMatchesPath(route.segments, path) {
+			continue
+		}
+
+		if nil == matched || ("" == matched.host && "" != route.host) || routeRelationMoreSpecial == routeCompare(route, matched) {
+			matched = route
+		}
+	}
+
+	return matched
+}
+
+// ServerRoutes lists all routes registered to the server, in order of registration.
+func ServerRoutes(self *Server) []RouteInfo {
+	var routes []RouteInfo
+	for _, route := range self.routes {
+		var guards []string
+		if route.isPage {
+			for _, guard := range self.pageGuards {
+				guards = append(guards, routeFunctionName(guard))
+			}
+		} else {
+			for _, guard := range self.apiGuards {
+				guards = append(guards, routeFunctionName(guard))
+			}
+		}
+
+		routes = append(routes, RouteInfo{
+			Method:  route.method,
+			Host:    route.host,
+			Path:    route.path,
+			Pattern: route.pattern,
+			Page:    route.page,
+			Handler: routeFunctionLocation(route.handler),
+			Guards:  guards,
+		})
+	}
+
+	return routes
+}
@@ -0,0 +1,101 @@
+package frizzante
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRouteParsePattern(test *testing.T) {
+	method, host, path := routeParsePattern("GET example.com/users/{id}")
+	if "GET" != method {
+		test.Fatalf("pattern was expected to have method 'GET', received '%s' instead", method)
+	}
+	if "example.com" != host {
+		test.Fatalf("pattern was expected to have host 'example.com', received '%s' instead", host)
+	}
+	if "/users/{id}" != path {
+		test.Fatalf("pattern was expected to have path '/users/{id}', received '%s' instead", path)
+	}
+
+	method, host, path = routeParsePattern("/")
+	if "" != method || "" != host || "/" != path {
+		test.Fatalf("pattern was expected to have only path '/', received method '%s', host '%s' and path '%s' instead", method, host, path)
+	}
+}
+
+func TestServerRoutes(test *testing.T) {
+	server := ServerCreate()
+	ServerWithApiGuard(server, func(req *Request, res *Response, pass func()) {
+		pass()
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /users/{id}")
+		serve(func(_ *Request, _ *Response) {})
+	})
+	ServerWithIndex(server, func(
+		route func(path string, page string),
+		show func(showFunction func(req *Request, res *Response, p *Page)),
+		action func(actionFunction func(req *Request, res *Response, p *Page)),
+	) {
+		route("/about", "about")
+	})
+
+	routes := ServerRoutes(server)
+	if 3 != len(routes) {
+		test.Fatalf("server was expected to have 3 routes, received %d instead", len(routes))
+	}
+
+	api := routes[0]
+	if "GET" != api.Method || "/users/{id}" != api.Path || "" != api.Page {
+		test.Fatalf("unexpected api route %+v", api)
+	}
+	if !strings.Contains(api.Handler, "libroute_test.go:") {
+		test.Fatalf("api route handler was expected to be located in libroute_test.go, received '%s' instead", api.Handler)
+	}
+	if 1 != len(api.Guards) {
+		test.Fatalf("api route was expected to have 1 guard, received %d instead", len(api.Guards))
+	}
+
+	show := routes[1]
+	if "GET" != show.Method || "about" != show.Page || 0 != len(show.Guards) {
+		test.Fatalf("unexpected show route %+v", show)
+	}
+
+	action := routes[2]
+	if "POST" != action.Method || "about" != action.Page {
+		test.Fatalf("unexpected action route %+v", action)
+	}
+}
+
+func TestServerMapRouteConflicts(test *testing.T) {
+	server := ServerCreate()
+	ServerWithNotifier(server, NotifierCreate())
+	noop := func(_ *Request, _ *Response) {}
+
+	// Neither of these is more specific, http.ServeMux would panic.
+	serverMapRoute(server, "GET /users/{id}", routeCreate(noop))
+	serverMapRoute(server, "GET /{collection}/me", routeCreate(noop))
+
+	// Shadowed by the first route.
+	serverMapRoute(server, "GET /users/{name}", routeCreate(noop))
+
+	// More specific, allowed.
+	serverMapRoute(server, "GET /users/me", routeCreate(noop))
+	serverMapRoute(server, "POST /users/{id}", routeCreate(noop))
+
+	// Fewer methods, but a more general path, http.ServeMux would panic.
+	serverMapRoute(server, "HEAD /users/{id}", routeCreate(noop))
+
+	var actual []string
+	for _, route := range ServerRoutes(server) {
+		actual = append(actual, route.Pattern)
+	}
+
+	expected := []string{"GET /users/{id}", "GET /users/me", "POST /users/{id}"}
+	if strings.Join(expected, ", ") != strings.Join(actual, ", ") {
+		test.Fatalf("server was expected to have routes '%s', received '%s' instead", strings.Join(expected, ", "), strings.Join(actual, ", "))
+	}
+}
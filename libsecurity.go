@@ -0,0 +1,128 @@
+package frizzante
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type SecurityHeaders struct {
+	headers               map[string]string
+	hstsMaxAge            time.Duration
+	hstsIncludeSubdomains bool
+	hstsPreload           bool
+	redirectToSecure      bool
+}
+
+// SecurityHeadersCreate creates a security headers preset.
+//
+// By default, the preset sends
+//
+//   - "X-Content-Type-Options: nosniff"
+//   - "X-Frame-Options: SAMEORIGIN"
+//   - "Referrer-Policy: strict-origin-when-cross-origin"
+//   - "Permissions-Policy: camera=(), microphone=(), geolocation=()"
+//   - "Cross-Origin-Opener-Policy: same-origin"
+//   - "Strict-Transport-Security: max-age=31536000; includeSubDomains", on secure connections only,
+//     including connections secured by a trusted proxy.
+func SecurityHeadersCreate() *SecurityHeaders {
+	return &SecurityHeaders{
+		headers: map[string]string{
+			"X-Content-Type-Options":     "nosniff",
+			"X-Frame-Options":            "SAMEORIGIN",
+			"Referrer-Policy":            "strict-origin-when-cross-origin",
+			"Permissions-Policy":         "camera=(), microphone=(), geolocation=()",
+			"Cross-Origin-Opener-Policy": "same-origin",
+		},
+		hstsMaxAge:            365 * 24 * time.Hour,
+		hstsIncludeSubdomains: true,
+		hstsPreload:           false,
+		redirectToSecure:      false,
+	}
+}
+
+// SecurityHeadersWithHeader sets a header of the preset.
+//
+// An empty value removes the header from the preset.
+func SecurityHeadersWithHeader(self *SecurityHeaders, key string, value string) {
+	if "" == value {
+		delete(self.headers, http.CanonicalHeaderKey(key))
+		return
+	}
+	self.headers[http.CanonicalHeaderKey(key)] = value
+}
+
+// SecurityHeadersWithStrictTransportSecurity configures the Strict-Transport-Security header.
+//
+// A maxAge of zero disables the header.
+func SecurityHeadersWithStrictTransportSecurity(self *SecurityHeaders, maxAge time.Duration, includeSubdomains bool, preload bool) {
+	self.hstsMaxAge = maxAge
+	self.hstsIncludeSubdomains = includeSubdomains
+	self.hstsPreload = preload
+}
+
+// SecurityHeadersWithRedirectToSecure sets whether insecure requests are redirected to the secure listener
+// using SendRedirectToSecure, so that clients receive the Strict-Transport-Security header.
+//
+// Requests are redirected only when the server has a certificate, or when it is behind a trusted proxy.
+func SecurityHeadersWithRedirectToSecure(self *SecurityHeaders, redirectToSecure bool) {
+	self.redirectToSecure = redirectToSecure
+}
+
+// ServerWithSecurityHeaders sets the security headers sent with all responses.
+//
+// Handlers can still override any of them using SendHeader.
+func ServerWithSecurityHeaders(self *Server, securityHeaders *SecurityHeaders) {
+	self.securityHeaders = securityHeaders
+}
+
+// ServerWithRouteSecurityHeaders sets the security headers sent with responses of the route mapped to pattern,
+// overriding the ones set with ServerWithSecurityHeaders.
+//
+// Use nil to send no security headers at all.
+func ServerWithRouteSecurityHeaders(self *Server, pattern string, securityHeaders *SecurityHeaders) {
+	self.routeSecurityHeaders[pattern] = securityHeaders
+}
+
+// serverSecurityHeaders finds the security headers of the route mapped to pattern.
+func serverSecurityHeaders(self *Server, pattern string) *SecurityHeaders {
+	securityHeaders, exists := self.routeSecurityHeaders[pattern]
+	if exists {
+		return securityHeaders
+	}
+
+	return self.securityHeaders
+}
+
+// securityHeadersSend sends the security headers of the route mapped to pattern.
+//
+// It returns false if the request has been redirected to the secure listener instead.
+func securityHeadersSend(self *Server, pattern string, response *Response) bool {
+	securityHeaders := serverSecurityHeaders(self, pattern)
+	if nil == securityHeaders {
+		return true
+	}
+
+	if securityHeaders.redirectToSecure && SendRedirectToSecure(response, http.StatusPermanentRedirect) {
+		SendEcho(response, "")
+		return false
+	}
+
+	header := *response.header
+	for key, value := range securityHeaders.headers {
+		header.Set(key, value)
+	}
+
+	if "https" == ReceiveScheme(response.request) && securityHeaders.hstsMaxAge > 0 {
+		value := fmt.Sprintf("max-age=%d", int64(securityHeaders.hstsMaxAge.Seconds()))
+		if securityHeaders.hstsIncludeSubdomains {
+			value += "; includeSubDomains"
+		}
+		if securityHeaders.hstsPreload {
+			value += "; preload"
+		}
+		header.Set("Strict-Transport-Security", value)
+	}
+
+	return true
+}
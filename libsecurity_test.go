@@ -0,0 +1,141 @@
+package frizzante
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// certificateCreate creates a self signed certificate for 127.0.0.1.
+func certificateCreate(test *testing.T) (string, string) {
+	key, keyError := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if keyError != nil {
+		test.Fatal(keyError)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	certificateBytes, certificateError := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if certificateError != nil {
+		test.Fatal(certificateError)
+	}
+
+	keyBytes, marshalError := x509.MarshalECPrivateKey(key)
+	if marshalError != nil {
+		test.Fatal(marshalError)
+	}
+
+	directory := test.TempDir()
+	certificate := filepath.Join(directory, "certificate.pem")
+	certificateKey := filepath.Join(directory, "key.pem")
+	writeError := os.WriteFile(certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificateBytes}), 0600)
+	if writeError != nil {
+		test.Fatal(writeError)
+	}
+	writeError = os.WriteFile(certificateKey, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600)
+	if writeError != nil {
+		test.Fatal(writeError)
+	}
+
+	return certificate, certificateKey
+}
+
+func TestServerWithSecurityHeaders(test *testing.T) {
+	certificate, certificateKey := certificateCreate(test)
+
+	server := ServerCreate()
+	port := NextNumber(8080)
+	securePort := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithSecurePort(server, securePort)
+	ServerWithCertificateAndKey(server, certificate, certificateKey)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithSecurityHeaders(server, SecurityHeadersCreate())
+
+	embeddable := SecurityHeadersCreate()
+	SecurityHeadersWithHeader(embeddable, "X-Frame-Options", "")
+	ServerWithRouteSecurityHeaders(server, "GET /widget", embeddable)
+
+	secure := SecurityHeadersCreate()
+	SecurityHeadersWithRedirectToSecure(secure, true)
+	ServerWithRouteSecurityHeaders(server, "GET /account", secure)
+
+	for _, pattern := range []string{"GET /hello", "GET /widget", "GET /account"} {
+		ServerWithApi(server, func(
+			route func(pattern string),
+			serve func(serveFunction func(req *Request, res *Response)),
+		) {
+			route(pattern)
+			serve(func(_ *Request, response *Response) {
+				SendEcho(response, "ok")
+			})
+		})
+	}
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	send := func(address string) *http.Response {
+		response, getError := client.Get(address)
+		if getError != nil {
+			test.Fatal(getError)
+		}
+		_ = response.Body.Close()
+		return response
+	}
+
+	insecure := send(fmt.Sprintf("http://127.0.0.1:%d/hello", port))
+	if "nosniff" != insecure.Header.Get("X-Content-Type-Options") || "SAMEORIGIN" != insecure.Header.Get("X-Frame-Options") {
+		test.Fatalf("response was expected to have security headers, received %v instead", insecure.Header)
+	}
+	if "" != insecure.Header.Get("Strict-Transport-Security") {
+		test.Fatal("insecure response was not expected to have the Strict-Transport-Security header")
+	}
+
+	secureResponse := send(fmt.Sprintf("https://127.0.0.1:%d/hello", securePort))
+	if "max-age=31536000; includeSubDomains" != secureResponse.Header.Get("Strict-Transport-Security") {
+		test.Fatalf("secure response was expected to have the Strict-Transport-Security header, received '%s' instead", secureResponse.Header.Get("Strict-Transport-Security"))
+	}
+
+	widget := send(fmt.Sprintf("http://127.0.0.1:%d/widget", port))
+	if "" != widget.Header.Get("X-Frame-Options") || "nosniff" != widget.Header.Get("X-Content-Type-Options") {
+		test.Fatalf("route override was expected to drop only X-Frame-Options, received %v instead", widget.Header)
+	}
+
+	account := send(fmt.Sprintf("http://127.0.0.1:%d/account?tab=1", port))
+	expected := fmt.Sprintf("https://127.0.0.1:%d/account?tab=1", securePort)
+	if 308 != account.StatusCode || expected != account.Header.Get("Location") {
+		test.Fatalf("insecure request was expected to be redirected to '%s', received status %d and location '%s' instead", expected, account.StatusCode, account.Header.Get("Location"))
+	}
+
+	accountSecure := send(expected)
+	if 200 != accountSecure.StatusCode || "" == accountSecure.Header.Get("Strict-Transport-Security") {
+		test.Fatalf("secure request was expected to succeed with the Strict-Transport-Security header, received status %d instead", accountSecure.StatusCode)
+	}
+}
@@ -17,8 +17,10 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,6 +31,7 @@ type Server struct {
 	multipartFormMaxMemory int64
 	server                 *http.Server
 	mux                    *http.ServeMux
+	routes                 []*Route
 	apiGuards              []func(req *Request, res *Response, pass func())
 	pageGuards             []func(req *Request, res *Response, p *Page, pass func())
 	sessions               map[string]*net.Conn
@@ -41,14 +44,56 @@ type Server struct {
 	temporaryDirectory     string
 	embeddedFileSystem     embed.FS
 	webSocketUpgrader      *websocket.Upgrader
-	sessionOperator        SessionOperator
-}
-
+	sessionOperator        SessionListingOperator
+	notFound               *statusPage
+	methodNotAllowed       *statusPage
+	requestTimeout         time.Duration
+	routeTimeouts          map[string]time.Duration
+	metrics                *Metrics
+	tracer                 *Tracer
+	healthEnabled          bool
+	healthChecks           []*HealthCheck
+	livenessPath           string
+	readinessPath          string
+	healthTimeout          time.Duration
+	shutdownDelay          time.Duration
+	stopping               atomic.Bool
+	cors                   *Cors
+	routeCors              map[string]*Cors
+	csrf                   bool
+	routeCsrf              map[string]bool
+	securityHeaders        *SecurityHeaders
+	routeSecurityHeaders   map[string]*SecurityHeaders
+	trustedProxies         []*net.IPNet
+	sessionCookie          *http.Cookie
+	sessionManager         *sessionManager
+	sessionIdleLifetime    time.Duration
+	sessionMaxLifetime     time.Duration
+	sessionSweepInterval   time.Duration
+	sessionSweeperStop     chan struct{}
+	sessionCleaner         func()
+	sessionStore           *sessionStore
+	sessionCodec           *SessionCodec
+	migrator               *Migrator
+	sql                    *Sql
+}
+
+type statusPage struct {
+	page string
+	show func(req *Request, res *Response, p *Page)
+}
+
+// SessionGetter retrieves a property from the session store,
+// or defaultValue if the store has no such property.
+//
+// The default value must be returned as is and must never be written to the store,
+// it's only meaningful to the caller, see SessionGet.
 type SessionGetter = func(key string, defaultValue any) (value any)
 type SessionSetter = func(key string, value any)
 type SessionUnsetter = func(key string)
 type SessionValidator = func() (valid bool)
 type SessionDestroyer = func()
+type SessionLister = func() (data map[string]any)
 
 type SessionOperator = func(
 	sessionId string,
@@ -59,17 +104,23 @@ type SessionOperator = func(
 	withDestroyer func(destroy SessionDestroyer),
 )
 
-type sessionStore struct {
-	createdAt      time.Time
-	lastActivityAt time.Time
-	data           map[string]any
-}
+// SessionListingOperator is a SessionOperator which can also list the data of a session.
+type SessionListingOperator = func(
+	sessionId string,
+	withGetter func(get SessionGetter),
+	withSetter func(set SessionSetter),
+	withUnsetter func(unset SessionUnsetter),
+	withValidator func(validate SessionValidator),
+	withDestroyer func(destroy SessionDestroyer),
+	withLister func(list SessionLister),
+)
 
 // ServerCreate creates a server.
 func ServerCreate() *Server {
-	var memory = map[string]sessionStore{}
+	metrics := MetricsCreate()
+	metricsDescribeServer(metrics)
 
-	return &Server{
+	server := &Server{
 		hostName:               "127.0.0.1",
 		port:                   8081,
 		securePort:             8383,
@@ -81,6 +132,24 @@ func ServerCreate() *Server {
 		pageGuards:             []func(req *Request, res *Response, p *Page, pass func()){},
 		readTimeout:            10 * time.Second,
 		writeTimeout:           10 * time.Second,
+		requestTimeout:         0,
+		routeTimeouts:          map[string]time.Duration{},
+		routeCors:              map[string]*Cors{},
+		csrf:                   true,
+		routeCsrf:              map[string]bool{},
+		routeSecurityHeaders:   map[string]*SecurityHeaders{},
+		trustedProxies:         []*net.IPNet{},
+		sessionOperator:        SessionOperatorMemoryCreate(),
+		sessionManager:         sessionManagerCreate(),
+		sessionStore:           sessionStoreOperatorCreate(),
+		sessionIdleLifetime:    30 * time.Minute,
+		sessionMaxLifetime:     0,
+		sessionSweepInterval:   time.Minute,
+		sessionCodec:           SessionCodecJsonCreate(),
+		metrics:                metrics,
+		livenessPath:           "/livez",
+		readinessPath:          "/readyz",
+		healthTimeout:          5 * time.Second,
 		maxHeaderBytes:         3 * MB,
 		certificate:            "",
 		certificateKey:         "",
@@ -90,60 +159,16 @@ func ServerCreate() *Server {
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 		},
-		sessionOperator: func(
-			sessionId string,
-			withGetter func(get SessionGetter),
-			withSetter func(set SessionSetter),
-			withUnsetter func(unset SessionUnsetter),
-			withValidator func(validate SessionValidator),
-			withDestroyer func(destroy SessionDestroyer),
-		) {
-
-			store, exists := memory[sessionId]
-			if !exists {
-				store = sessionStore{
-					data:           map[string]any{},
-					createdAt:      time.Now(),
-					lastActivityAt: time.Now(),
-				}
-				memory[sessionId] = store
-			}
-
-			withGetter(func(key string, defaultValue any) (value any) {
-				sessionItem, ok := store.data[key]
-				if !ok {
-					store.data[key] = defaultValue
-					value = defaultValue
-					store.lastActivityAt = time.Now()
-					return
-				}
-
-				store.lastActivityAt = time.Now()
-				value = sessionItem
-				return
-			})
-
-			withSetter(func(key string, value any) {
-				store.lastActivityAt = time.Now()
-				store.data[key] = value
-			})
-
-			withUnsetter(func(key string) {
-				store.lastActivityAt = time.Now()
-				delete(store.data, key)
-			})
-
-			withValidator(func() (valid bool) {
-				elapsedSeconds := time.Since(store.lastActivityAt).Minutes()
-				valid = elapsedSeconds < 30
-				return
-			})
-
-			withDestroyer(func() {
-				delete(memory, sessionId)
-			})
+		sessionCookie: &http.Cookie{
+			Name:     "session-id",
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
 		},
 	}
+
+	metricsCollectServer(server, metrics)
+	return server
 }
 
 // ServerWithWebSocketReadBufferSize sets the maximum buffer size for each incoming web socket message.
@@ -188,6 +213,24 @@ func ServerWithWriteTimeout(self *Server, writeTimeout time.Duration) {
 	self.writeTimeout = writeTimeout
 }
 
+// ServerWithRequestTimeout sets the default timeout of each request.
+//
+// When the timeout expires, the context of the request is canceled,
+// see ReceiveContext.
+//
+// A timeout of 0 disables the timeout, which is the default.
+func ServerWithRequestTimeout(self *Server, requestTimeout time.Duration) {
+	self.requestTimeout = requestTimeout
+}
+
+// ServerWithRouteTimeout sets the timeout of each request matching the given pattern,
+// overriding the default request timeout.
+//
+// The pattern must be exactly the same as the one used to register the route.
+func ServerWithRouteTimeout(self *Server, pattern string, timeout time.Duration) {
+	self.routeTimeouts[pattern] = timeout
+}
+
 // ServerWithMaxHeaderBytes sets the maximum allowed bytes in the header of the request.
 func ServerWithMaxHeaderBytes(self *Server, maxHeaderBytes int) {
 	self.maxHeaderBytes = maxHeaderBytes
@@ -340,7 +383,7 @@ func ServerTemporaryDirectoryClear(self *Server) {
 func ReceiveCookie(self *Request, key string) string {
 	cookie, cookieError := self.httpRequest.Cookie(key)
 	if cookieError != nil {
-		NotifierSendError(self.server.notifier, cookieError)
+		NotifierSendError(self.notifier, cookieError)
 		return ""
 	}
 	value, unescapeError := url.QueryUnescape(cookie.Value)
@@ -351,6 +394,38 @@ func ReceiveCookie(self *Request, key string) string {
 	return value
 }
 
+// ReceiveRequestId returns the id of the request.
+//
+// The id is taken from the X-Request-Id header of the request when present,
+// otherwise it is generated.
+//
+// Compatible with web sockets.
+func ReceiveRequestId(self *Request) string {
+	return self.id
+}
+
+// ReceiveNotifier returns the notifier of the request,
+// which tags all errors with the id of the request.
+//
+// Compatible with web sockets.
+func ReceiveNotifier(self *Request) *Notifier {
+	return self.notifier
+}
+
+// ReceiveContext returns the context of the request.
+//
+// The context is canceled when the client disconnects,
+// when the request times out or when the server handler returns.
+//
+// Pass it to functions like SqlFindWithContext or HttpGetWithContext
+// in order to stop work as soon as the request is no longer relevant,
+// errors these functions send to their notifiers are then tagged with the id of the request.
+//
+// Compatible with web sockets.
+func ReceiveContext(self *Request) context.Context {
+	return self.httpRequest.Context()
+}
+
 // ReceiveMessage reads the contents of the message and returns the value.
 //
 // Compatible with web sockets.
@@ -358,7 +433,7 @@ func ReceiveMessage(self *Request) string {
 	if self.webSocketConn != nil {
 		_, readBytes, readError := self.webSocketConn.ReadMessage()
 		if readError != nil {
-			NotifierSendError(self.server.notifier, readError)
+			NotifierSendError(self.notifier, readError)
 			return ""
 		}
 		return string(readBytes)
@@ -366,7 +441,7 @@ func ReceiveMessage(self *Request) string {
 
 	readBytes, readAllError := io.ReadAll(self.httpRequest.Body)
 	if readAllError != nil {
-		NotifierSendError(self.server.notifier, readAllError)
+		NotifierSendError(self.notifier, readAllError)
 		return ""
 	}
 	return string(readBytes)
@@ -381,7 +456,7 @@ func ReceiveJson[T any](self *Request) (*T, bool) {
 	if self.webSocketConn != nil {
 		jsonError := self.webSocketConn.ReadJSON(value)
 		if jsonError != nil {
-			NotifierSendError(self.server.notifier, jsonError)
+			NotifierSendError(self.notifier, jsonError)
 			return nil, false
 		}
 		return &value, true
@@ -389,12 +464,12 @@ func ReceiveJson[T any](self *Request) (*T, bool) {
 
 	readBytes, readAllError := io.ReadAll(self.httpRequest.Body)
 	if readAllError != nil {
-		NotifierSendError(self.server.notifier, readAllError)
+		NotifierSendError(self.notifier, readAllError)
 		return nil, false
 	}
 	unmarshalError := json.Unmarshal(readBytes, &value)
 	if unmarshalError != nil {
-		NotifierSendError(self.server.notifier, unmarshalError)
+		NotifierSendError(self.notifier, unmarshalError)
 		return nil, false
 	}
 	return &value, true
@@ -403,19 +478,19 @@ func ReceiveJson[T any](self *Request) (*T, bool) {
 // ReceiveForm reads the message as a form and returns the value.
 func ReceiveForm(self *Request) *url.Values {
 	if self.webSocketConn != nil {
-		NotifierSendError(self.server.notifier, errors.New("web socket connections cannot receive form payloads"))
+		NotifierSendError(self.notifier, errors.New("web socket connections cannot receive form payloads"))
 		return &url.Values{}
 	}
 
 	parseMultipartFormError := self.httpRequest.ParseMultipartForm(self.server.multipartFormMaxMemory)
 	if parseMultipartFormError != nil {
 		if !errors.Is(parseMultipartFormError, http.ErrNotMultipart) {
-			NotifierSendError(self.server.notifier, parseMultipartFormError)
+			NotifierSendError(self.notifier, parseMultipartFormError)
 		}
 
 		parseFormError := self.httpRequest.ParseForm()
 		if parseFormError != nil {
-			NotifierSendError(self.server.notifier, parseFormError)
+			NotifierSendError(self.notifier, parseFormError)
 		}
 	}
 
@@ -450,24 +525,135 @@ func ReceiveContentType(self *Request) string {
 	return self.httpRequest.Header.Get("Content-Type")
 }
 
+// ServerWithNotFound sets the page used to respond when no route matches the request.
+//
+// The show function is optional and can be used to customize the page before it's sent,
+// the page data contains the status code and message by default.
+//
+// Requests accepting "application/json" receive the page data as json instead.
+//
+// When page is empty, a plain text response is sent instead of a svelte page.
+func ServerWithNotFound(self *Server, page string, show func(req *Request, res *Response, p *Page)) {
+	self.notFound = &statusPage{page: page, show: show}
+}
+
+// ServerWithMethodNotAllowed sets the page used to respond when a route matches
+// the path of the request, but not its method.
+//
+// The show function is optional and can be used to customize the page before it's sent,
+// the page data contains the status code and message by default.
+//
+// Requests accepting "application/json" receive the page data as json instead.
+//
+// When page is empty, a plain text response is sent instead of a svelte page.
+//
+// The response always includes an Allow header listing the methods registered for the path.
+func ServerWithMethodNotAllowed(self *Server, page string, show func(req *Request, res *Response, p *Page)) {
+	self.methodNotAllowed = &statusPage{page: page, show: show}
+}
+
+// sendStatusPage sends a status code along with a svelte page, json data or plain text,
+// depending on the status page configuration and on what the request accepts.
+func sendStatusPage(self *Response, statusCode int, configuration *statusPage) {
+	request := self.request
+	if nil == configuration {
+		configuration = &statusPage{}
+	}
+
+	p := &Page{
+		render: RenderFull,
+		data: map[string]any{
+			"status":  statusCode,
+			"message": http.StatusText(statusCode),
+		},
+		efs:        request.server.embeddedFileSystem,
+		name:       configuration.page,
+		parameters: map[string]string{},
+		request:    request,
+	}
+
+	SendStatus(self, statusCode)
+
+	if nil != configuration.show {
+		configuration.show(request, self, p)
+	}
+
+	if self.lockedStatusAndHeader || "" != self.header.Get("Location") {
+		return
+	}
+
+	if VerifyAccept(request, "application/json") {
+		SendJson(self, p.data)
+		return
+	}
+
+	if "" == configuration.page {
+		SendEcho(self, http.StatusText(self.statusCode))
+		return
+	}
+
+	SendPage(self, p)
+}
+
+func sendNotFoundPage(self *Response) {
+	sendStatusPage(self, http.StatusNotFound, self.server.notFound)
+}
+
+func sendMethodNotAllowedPage(self *Response, methods []string) {
+	SendHeader(self, "Allow", strings.Join(methods, ", "))
+	sendStatusPage(self, http.StatusMethodNotAllowed, self.server.methodNotAllowed)
+}
+
+func notFoundServe(req *Request, res *Response) {
+	sendNotFoundPage(res)
+}
+
 func notFoundApi(
 	route func(pattern string),
 	serve func(serveFunction func(req *Request, res *Response)),
 ) {
 	route("GET /")
-	serve(func(req *Request, res *Response) {
-		SendStatus(res, 404)
+	serve(notFoundServe)
+}
+
+// serverHandler creates the root handler of the server.
+//
+// Requests matching a route are dispatched by the mux,
+// preflight requests for apis with a cors configuration are answered automatically,
+// all others are answered with either 405 Method Not Allowed or 404 Not Found.
+func serverHandler(self *Server) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, httpRequest *http.Request) {
+		_, pattern := self.mux.Handler(httpRequest)
+		if "" != pattern {
+			self.mux.ServeHTTP(writer, httpRequest)
+			return
+		}
+
+		serverHandle(self, "", writer, httpRequest, func(request *Request, response *Response) {
+			if corsPreflight(self, request, response) {
+				return
+			}
+
+			methods := routeAllowedMethods(self, httpRequest)
+			if len(methods) > 0 {
+				sendMethodNotAllowedPage(response, methods)
+				return
+			}
+			sendNotFoundPage(response)
+		})
 	})
 }
 
 // ServerStart starts the server.
 //
+// ServerStart blocks until the server is stopped with ServerStop.
+//
 // If the server fails to start, ServerStart panics.
 func ServerStart(self *Server) {
 	logger := log.New(self.notifier.errorFile, "<error>", log.Ltime|log.Llongfile)
 
 	self.server = &http.Server{
-		Handler:        self.mux,
+		Handler:        serverHandler(self),
 		ReadTimeout:    self.readTimeout,
 		WriteTimeout:   self.writeTimeout,
 		MaxHeaderBytes: self.maxHeaderBytes,
@@ -478,14 +664,24 @@ func ServerStart(self *Server) {
 		ServerWithApi(self, notFoundApi)
 	}
 
+	self.stopping.Store(false)
+	self.sessionSweeperStop = make(chan struct{})
+	go sessionSweeperStart(self, self.sessionSweeperStop)
+
 	var waiter sync.WaitGroup
 
 	waiter.Add(2)
 
 	go func() {
+		defer waiter.Done()
 		address := fmt.Sprintf("%s:%d", self.hostName, self.port)
+		listener, listenError := net.Listen("tcp", address)
+		if listenError != nil {
+			panic(listenError.Error())
+		}
+
 		NotifierSendMessage(self.notifier, fmt.Sprintf("listening for requests at http://%s", address))
-		err := http.ListenAndServe(address, self.mux)
+		err := self.server.Serve(listener)
 		if err != nil {
 			if errors.Is(err, http.ErrServerClosed) {
 				NotifierSendMessage(self.notifier, "shutting down server")
@@ -496,10 +692,16 @@ func ServerStart(self *Server) {
 	}()
 
 	go func() {
+		defer waiter.Done()
 		secureAddress := fmt.Sprintf("%s:%d", self.hostName, self.securePort)
 		if "" != self.certificate && "" != self.certificateKey {
+			listener, listenError := net.Listen("tcp", secureAddress)
+			if listenError != nil {
+				panic(listenError.Error())
+			}
+
 			NotifierSendMessage(self.notifier, fmt.Sprintf("listening for requests at https://%s", secureAddress))
-			err := http.ListenAndServeTLS(secureAddress, self.certificate, self.certificateKey, self.mux)
+			err := self.server.ServeTLS(listener, self.certificate, self.certificateKey)
 			if err != nil {
 				if errors.Is(err, http.ErrServerClosed) {
 					NotifierSendMessage(self.notifier, "shutting down server")
@@ -513,10 +715,31 @@ func ServerStart(self *Server) {
 	waiter.Wait()
 }
 
-// ServerStop attempts to stop the server.
+// ServerWithShutdownDelay sets for how long ServerStop keeps serving requests
+// after the readiness check starts failing, which gives load balancers
+// time to stop routing new requests to the server.
+func ServerWithShutdownDelay(self *Server, shutdownDelay time.Duration) {
+	self.shutdownDelay = shutdownDelay
+}
+
+// ServerStop gracefully stops the server.
+//
+// The readiness check starts failing immediately, then, after the shutdown delay,
+// the server stops accepting connections and waits for pending requests to complete.
 //
 // If the shutdown attempt fails, ServerStop panics.
 func ServerStop(self *Server) {
+	self.stopping.Store(true)
+
+	if self.shutdownDelay > 0 {
+		time.Sleep(self.shutdownDelay)
+	}
+
+	if nil != self.sessionSweeperStop {
+		close(self.sessionSweeperStop)
+		self.sessionSweeperStop = nil
+	}
+
 	err := self.server.Shutdown(context.Background())
 	if err != nil {
 		panic(err.Error())
@@ -529,6 +752,12 @@ type Route struct {
 	server   *Server
 	isPage   bool
 	page     string
+	pattern  string
+	method   string
+	host     string
+	path     string
+	segments []routeSegment
+	handler  any
 	callback func(request *Request, response *Response)
 	mount    func(pattern string)
 }
@@ -541,13 +770,16 @@ func routeCreate(
 	),
 ) *Route {
 	return &Route{
-		isPage: false,
-		page:   "",
+		isPage:  false,
+		page:    "",
+		handler: callback,
 		callback: func(request *Request, response *Response) {
 			for _, guard := range response.server.apiGuards {
 				pass := false
-				guard(request, response, func() {
-					pass = true
+				requestSpan(request, "guard "+routeFunctionName(guard), func() {
+					guard(request, response, func() {
+						pass = true
+					})
 				})
 
 				if !pass {
@@ -555,7 +787,9 @@ func routeCreate(
 				}
 			}
 
-			callback(request, response)
+			requestSpan(request, "handler "+routeFunctionName(callback), func() {
+				callback(request, response)
+			})
 		},
 		mount: func(pattern string) {},
 	}
@@ -576,8 +810,9 @@ func routeCreateWithPage(
 	var pattern string
 
 	return &Route{
-		isPage: true,
-		page:   page,
+		isPage:  true,
+		page:    page,
+		handler: callback,
 		callback: func(
 			request *Request,
 			response *Response,
@@ -588,12 +823,15 @@ func routeCreateWithPage(
 				efs:        request.server.embeddedFileSystem,
 				name:       page,
 				parameters: map[string]string{},
+				request:    request,
 			}
 
 			for _, guard := range response.server.pageGuards {
 				pass := false
-				guard(request, response, p, func() {
-					pass = true
+				requestSpan(request, "guard "+routeFunctionName(guard), func() {
+					guard(request, response, p, func() {
+						pass = true
+					})
 				})
 
 				if !pass {
@@ -601,7 +839,9 @@ func routeCreateWithPage(
 				}
 			}
 
-			callback(request, response, p)
+			requestSpan(request, "handler "+routeFunctionName(callback), func() {
+				callback(request, response, p)
+			})
 
 			if nil != response.navigate {
 				SendRedirect(response, response.navigate.Location, http.StatusFound)
@@ -613,7 +853,7 @@ func routeCreateWithPage(
 			}
 
 			if nil == p {
-				NotifierSendError(request.server.notifier, fmt.Errorf("svelte page handler `%s` returned a nil page", pattern))
+				NotifierSendError(request.notifier, fmt.Errorf("svelte page handler `%s` returned a nil page", pattern))
 				return
 			}
 
@@ -622,9 +862,15 @@ func routeCreateWithPage(
 			}
 
 			if VerifyAccept(request, "application/json") {
+				// The token may have changed since the page was rendered, like when the session is regenerated.
+				if csrf := pageCsrf(p); "" != csrf {
+					SendHeader(response, csrfHeaderName, csrf)
+				}
+
+				pageFlash(p)
 				data, marshalError := json.Marshal(p.data)
 				if marshalError != nil {
-					NotifierSendError(request.server.notifier, marshalError)
+					NotifierSendError(request.notifier, marshalError)
 					return
 				}
 				SendHeader(response, "Content-Type", "application/json")
@@ -660,12 +906,25 @@ var entryCreated = false
 
 // serverMapRoute maps a pattern to a given route.
 //
-// If the given pattern conflicts with one that is already registered, serverMapRoute panics.
+// If the given pattern conflicts with or is shadowed by one that is already registered,
+// the route is not mapped and the server is notified.
 func serverMapRoute(
 	self *Server,
 	pattern string,
 	route *Route,
 ) {
+	route.pattern = pattern
+	route.method, route.host, route.path = routeParsePattern(pattern)
+	route.segments = routeParseSegments(route.path)
+
+	conflictError := routeVerifyConflicts(self, route)
+	if conflictError != nil {
+		NotifierSendError(self.notifier, conflictError)
+		return
+	}
+
+	self.routes = append(self.routes, route)
+
 	patternParts := strings.Split(pattern, " ")
 	patternCounter := len(patternParts)
 	isEntry := patternCounter > 1 && strings.HasPrefix(strings.TrimPrefix(filepath.Join(patternParts[1:]...), " "), "/")
@@ -679,57 +938,157 @@ func serverMapRoute(
 	}
 
 	self.mux.HandleFunc(pattern, func(writer http.ResponseWriter, httpRequest *http.Request) {
-		request := Request{
-			server:      self,
-			httpRequest: httpRequest,
+		timeout, timeoutFound := self.routeTimeouts[pattern]
+		if !timeoutFound {
+			timeout = self.requestTimeout
 		}
 
-		httpHeader := writer.Header()
-
-		response := Response{
-			server:                self,
-			writer:                &writer,
-			lockedStatusAndHeader: false,
-			statusCode:            200,
-			header:                &httpHeader,
-			eventName:             "",
-			eventId:               1,
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(httpRequest.Context(), timeout)
+			defer cancel()
+			httpRequest = httpRequest.WithContext(ctx)
 		}
 
-		request.response = &response
-		response.request = &request
+		serverHandle(self, pattern, writer, httpRequest, func(request *Request, response *Response) {
+			if !corsVerify(self, route, request, response) {
+				return
+			}
 
-		if isEntry {
-			SendEmbeddedFileOrElse(&response, func() {
-				SendFileOrElse(&response, func() {
-					if route.callback != nil {
-						if "/favicon.ico" == request.httpRequest.RequestURI {
-							SendNotFound(&response)
-							return
-						}
-						route.callback(&request, &response)
+			if !csrfVerify(self, route, request, response) {
+				return
+			}
 
-						if !response.lockedStatusAndHeader {
-							SendEcho(&response, "")
+			if isEntry {
+				SendEmbeddedFileOrElse(response, func() {
+					SendFileOrElse(response, func() {
+						if route.callback != nil {
+							if "/favicon.ico" == request.httpRequest.RequestURI {
+								SendNotFound(response)
+								return
+							}
+
+							if route.isPage && routeMatchesPrefixOnly(route, request.httpRequest.URL.Path) {
+								// Pages mapped to a path with a trailing slash only match
+								// that exact path, anything below it is not found.
+								sendNotFoundPage(response)
+								return
+							}
+
+							route.callback(request, response)
+
+							if !response.lockedStatusAndHeader {
+								SendEcho(response, "")
+							}
 						}
-					}
+					})
 				})
-			})
-		} else if route.callback != nil {
-			route.callback(&request, &response)
+			} else if route.callback != nil {
+				route.callback(request, response)
 
-			if !response.lockedStatusAndHeader {
-				SendEcho(&response, "")
+				if !response.lockedStatusAndHeader {
+					SendEcho(response, "")
+				}
 			}
-		}
+		})
 	})
 }
 
+// serverHandle wraps an http request and its response writer, then handles them.
+//
+// Each request is assigned an id, which is sent back to the client
+// with the X-Request-Id header and attached to all errors sent to the notifier
+// while handling the request.
+//
+// The pattern is the one of the route handling the request, empty when no route matches.
+func serverHandle(
+	self *Server,
+	pattern string,
+	writer http.ResponseWriter,
+	httpRequest *http.Request,
+	handle func(request *Request, response *Response),
+) {
+	startedAt := time.Now()
+	requestId := requestIdCreate(self, httpRequest)
+	notifier := notifierWithRequestId(self.notifier, requestId)
+	httpRequest = httpRequest.WithContext(context.WithValue(httpRequest.Context(), requestIdContextKey{}, requestId))
+	accessWriter := &accessWriter{writer: writer}
+	writer = accessWriter
+
+	var span *Span
+	if nil != self.tracer {
+		spanName := pattern
+		if "" == spanName {
+			spanName = httpRequest.Method
+		}
+
+		var ctx context.Context
+		ctx, span = tracerSpanStart(self.tracer, httpRequest.Context(), spanName, SpanKindServer, httpRequest.Header.Get("traceparent"))
+		SpanWithAttribute(span, "http.request.method", httpRequest.Method)
+		SpanWithAttribute(span, "http.route", pattern)
+		SpanWithAttribute(span, "url.path", httpRequest.URL.Path)
+		SpanWithAttribute(span, "request.id", requestId)
+		httpRequest = httpRequest.WithContext(ctx)
+	}
+
+	request := Request{
+		id:          requestId,
+		server:      self,
+		notifier:    notifier,
+		httpRequest: httpRequest,
+	}
+
+	httpHeader := writer.Header()
+	httpHeader.Set("X-Request-Id", requestId)
+
+	response := Response{
+		server:                self,
+		notifier:              notifier,
+		writer:                &writer,
+		lockedStatusAndHeader: false,
+		statusCode:            200,
+		header:                &httpHeader,
+		eventName:             "",
+		eventId:               1,
+	}
+
+	request.response = &response
+	response.request = &request
+
+	if securityHeadersSend(self, pattern, &response) {
+		handle(&request, &response)
+	}
+
+	entry := accessEntryCreate(&request, accessWriter, startedAt)
+	if nil != span {
+		SpanWithAttribute(span, "http.response.status_code", entry.Status)
+		if entry.Status >= 500 {
+			SpanWithError(span, fmt.Errorf("server responded with status code '%d'", entry.Status))
+		}
+		SpanEnd(span)
+	}
+	MetricsCounterAdd(self.metrics, "frizzante_http_requests_total", map[string]string{
+		"method":  httpRequest.Method,
+		"pattern": pattern,
+		"status":  strconv.Itoa(entry.Status),
+	}, 1)
+	MetricsHistogramObserve(self.metrics, "frizzante_http_request_duration_seconds", map[string]string{
+		"method":  httpRequest.Method,
+		"pattern": pattern,
+	}, time.Since(startedAt).Seconds())
+	NotifierSendAccess(self.notifier, entry)
+}
+
 type Request struct {
+	id            string
 	server        *Server
+	notifier      *Notifier
 	response      *Response
 	httpRequest   *http.Request
 	webSocketConn *websocket.Conn
+	sessionId     string
+	session       *Session
+	flash         map[string]any
+	forwarded     *forwardedElement
 }
 
 type Navigate struct {
@@ -740,6 +1099,7 @@ type Navigate struct {
 
 type Response struct {
 	server                *Server
+	notifier              *Notifier
 	request               *Request
 	writer                *http.ResponseWriter
 	lockedStatusAndHeader bool
@@ -759,7 +1119,7 @@ func SendNavigateWithParameters(self *Response, page string, parameters map[stri
 
 	p, pathFound := pages[page]
 	if !pathFound {
-		NotifierSendError(self.server.notifier, fmt.Errorf("redirect to page `%s` failed because page id `%s` is unknown", page, page))
+		NotifierSendError(self.notifier, fmt.Errorf("redirect to page `%s` failed because page id `%s` is unknown", page, page))
 		return
 	}
 
@@ -798,18 +1158,38 @@ var pathFieldRegex = regexp.MustCompile(`\{(.*?)}`)
 
 // SendRedirectToSecure tries to redirect the request to the https server.
 //
-// When the request is already secure, SendRedirectToSecure returns false.
+// Behind a trusted proxy, the request is redirected to the default https port of the host the client sent the request to,
+// otherwise it is redirected to the secure port of the server, which requires a certificate.
+//
+// When the request is already secure, or there is no https server to redirect to, SendRedirectToSecure returns false.
 func SendRedirectToSecure(self *Response, statusCode int) bool {
 	request := self.request
-	if "" == request.server.certificate || "" == request.server.certificateKey || request.httpRequest.TLS != nil {
+	if "https" == ReceiveScheme(request) {
 		return false
 	}
 
-	insecureSuffix := fmt.Sprintf(":%d", request.server.port)
-	secureSuffix := fmt.Sprintf(":%d", request.server.securePort)
-	secureHost := strings.Replace(request.httpRequest.Host, insecureSuffix, secureSuffix, 1)
-	secureLocation := fmt.Sprintf("https://%s%s", secureHost, request.httpRequest.RequestURI)
-	SendRedirect(self, secureLocation, 302)
+	hostName := ReceiveHost(request)
+	if splitHostName, _, splitError := net.SplitHostPort(hostName); nil == splitError {
+		hostName = splitHostName
+	}
+
+	secureHost := hostName
+	if strings.Contains(hostName, ":") {
+		secureHost = "[" + hostName + "]"
+	}
+
+	if !requestIsForwarded(request) {
+		if "" == request.server.certificate || "" == request.server.certificateKey {
+			return false
+		}
+
+		if 443 != request.server.securePort {
+			secureHost = net.JoinHostPort(hostName, strconv.Itoa(request.server.securePort))
+		}
+	}
+
+	secureLocation := fmt.Sprintf("https://%s%s", secureHost, request.httpRequest.URL.RequestURI())
+	SendRedirect(self, secureLocation, statusCode)
 	return true
 }
 
@@ -822,7 +1202,7 @@ func SendRedirectToSecure(self *Response, statusCode int) bool {
 // You can retrieve the error using ServerRecallError.
 func SendStatus(self *Response, code int) {
 	if self.lockedStatusAndHeader {
-		NotifierSendError(self.server.notifier, errors.New("status is locked"))
+		NotifierSendError(self.notifier, errors.New("status is locked"))
 		return
 	}
 	self.statusCode = code
@@ -837,7 +1217,7 @@ func SendStatus(self *Response, code int) {
 // You can retrieve the error using ServerRecallError
 func SendHeader(self *Response, key string, value string) {
 	if self.lockedStatusAndHeader {
-		NotifierSendError(self.server.notifier, errors.New("headers locked"))
+		NotifierSendError(self.notifier, errors.New("headers locked"))
 		return
 	}
 
@@ -849,9 +1229,51 @@ func SendContentType(self *Response, contentType string) {
 	SendHeader(self, "Content-Type", contentType)
 }
 
-// SendCookie sends a cookies to the client.
-func SendCookie(self *Response, key string, value string) {
-	SendHeader(self, "Set-Cookie", fmt.Sprintf("%s=%s; Path=/; HttpOnly", url.QueryEscape(key), url.QueryEscape(value)))
+// SendCookie sends a cookie to the client.
+//
+// Both the name and the value of the cookie are query escaped, see ReceiveCookie.
+//
+// The path of the cookie defaults to "/".
+func SendCookie(self *Response, cookie *http.Cookie) {
+	if self.lockedStatusAndHeader {
+		NotifierSendError(self.notifier, errors.New("headers locked"))
+		return
+	}
+
+	escaped := *cookie
+	escaped.Name = url.QueryEscape(cookie.Name)
+	escaped.Value = url.QueryEscape(cookie.Value)
+	if "" == escaped.Path {
+		escaped.Path = "/"
+	}
+
+	line := escaped.String()
+	if "" == line {
+		NotifierSendError(self.notifier, fmt.Errorf("cookie `%s` is not valid", cookie.Name))
+		return
+	}
+
+	self.header.Add("Set-Cookie", line)
+}
+
+// sendCookieWithdraw removes all cookies with the given name from the response, as long as headers are not locked.
+func sendCookieWithdraw(self *Response, name string) {
+	if self.lockedStatusAndHeader {
+		return
+	}
+
+	prefix := url.QueryEscape(name) + "="
+	var lines []string
+	for _, line := range self.header.Values("Set-Cookie") {
+		if !strings.HasPrefix(line, prefix) {
+			lines = append(lines, line)
+		}
+	}
+
+	self.header.Del("Set-Cookie")
+	for _, line := range lines {
+		self.header.Add("Set-Cookie", line)
+	}
 }
 
 // SendContent sends binary safe content.
@@ -878,7 +1300,7 @@ func SendContent(self *Response, content []byte) {
 	if self.webSocket != nil {
 		writeError := self.webSocket.WriteMessage(websocket.TextMessage, content)
 		if writeError != nil {
-			NotifierSendError(self.server.notifier, writeError)
+			NotifierSendError(self.notifier, writeError)
 		}
 		return
 	}
@@ -890,7 +1312,7 @@ func SendContent(self *Response, content []byte) {
 
 	_, err := (*self.writer).Write(content)
 	if err != nil {
-		NotifierSendError(self.server.notifier, err)
+		NotifierSendError(self.notifier, err)
 		return
 	}
 }
@@ -945,7 +1367,7 @@ func SendTooManyRequests(self *Response) {
 func SendJson(self *Response, payload any) {
 	content, marshalError := json.Marshal(payload)
 	if marshalError != nil {
-		NotifierSendError(self.server.notifier, marshalError)
+		NotifierSendError(self.notifier, marshalError)
 	}
 
 	if nil == self.webSocket {
@@ -987,39 +1409,39 @@ func sendEventContent(self *Response, content []byte) {
 
 	_, writeEventError := (*self.writer).Write([]byte(header))
 	if writeEventError != nil {
-		NotifierSendError(self.server.notifier, writeEventError)
+		NotifierSendError(self.notifier, writeEventError)
 		return
 	}
 
 	for _, line := range bytes.Split(content, []byte("\r\n")) {
 		_, writeEventError = (*self.writer).Write([]byte("data: "))
 		if writeEventError != nil {
-			NotifierSendError(self.server.notifier, writeEventError)
+			NotifierSendError(self.notifier, writeEventError)
 			return
 		}
 
 		_, writeEventError = (*self.writer).Write(line)
 		if writeEventError != nil {
-			NotifierSendError(self.server.notifier, writeEventError)
+			NotifierSendError(self.notifier, writeEventError)
 			return
 		}
 
 		_, writeEventError = (*self.writer).Write([]byte("\r\n"))
 		if writeEventError != nil {
-			NotifierSendError(self.server.notifier, writeEventError)
+			NotifierSendError(self.notifier, writeEventError)
 			return
 		}
 	}
 
 	_, writeEventError = (*self.writer).Write([]byte("\r\n"))
 	if writeEventError != nil {
-		NotifierSendError(self.server.notifier, writeEventError)
+		NotifierSendError(self.notifier, writeEventError)
 		return
 	}
 
 	flusher, flushedOk := (*self.writer).(http.Flusher)
 	if !flushedOk {
-		NotifierSendError(self.server.notifier, errors.New("could not retrieve flusher"))
+		NotifierSendError(self.notifier, errors.New("could not retrieve flusher"))
 		return
 	}
 
@@ -1049,19 +1471,19 @@ func SendEmbeddedFileOrIndexOrElse(self *Response, orElse func()) {
 
 	reader, info, readerError := createReaderFromEmbeddedFileName(&request.server.embeddedFileSystem, fileName)
 	if readerError != nil {
-		NotifierSendError(self.server.notifier, readerError)
+		NotifierSendError(self.notifier, readerError)
 		return
 	}
 
 	if self.webSocket != nil {
 		content, readError := io.ReadAll(reader)
 		if readError != nil {
-			NotifierSendError(self.server.notifier, readError)
+			NotifierSendError(self.notifier, readError)
 			return
 		}
 		writeError := self.webSocket.WriteMessage(websocket.TextMessage, content)
 		if writeError != nil {
-			NotifierSendError(self.server.notifier, writeError)
+			NotifierSendError(self.notifier, writeError)
 		}
 		return
 	}
@@ -1069,7 +1491,7 @@ func SendEmbeddedFileOrIndexOrElse(self *Response, orElse func()) {
 	if "" != self.eventName {
 		content, readError := io.ReadAll(reader)
 		if readError != nil {
-			NotifierSendError(self.server.notifier, readError)
+			NotifierSendError(self.notifier, readError)
 			return
 		}
 		sendEventContent(self, content)
@@ -1102,19 +1524,19 @@ func SendEmbeddedFileOrElse(self *Response, orElse func()) {
 
 	reader, info, readerError := createReaderFromEmbeddedFileName(&request.server.embeddedFileSystem, fileName)
 	if readerError != nil {
-		NotifierSendError(self.server.notifier, readerError)
+		NotifierSendError(self.notifier, readerError)
 		return
 	}
 
 	if self.webSocket != nil {
 		content, readError := io.ReadAll(reader)
 		if readError != nil {
-			NotifierSendError(self.server.notifier, readError)
+			NotifierSendError(self.notifier, readError)
 			return
 		}
 		writeError := self.webSocket.WriteMessage(websocket.TextMessage, content)
 		if writeError != nil {
-			NotifierSendError(self.server.notifier, writeError)
+			NotifierSendError(self.notifier, writeError)
 		}
 		return
 	}
@@ -1122,7 +1544,7 @@ func SendEmbeddedFileOrElse(self *Response, orElse func()) {
 	if "" != self.eventName {
 		content, readError := io.ReadAll(reader)
 		if readError != nil {
-			NotifierSendError(self.server.notifier, readError)
+			NotifierSendError(self.notifier, readError)
 			return
 		}
 		sendEventContent(self, content)
@@ -1160,19 +1582,19 @@ func SendFileOrIndexOrElse(self *Response, orElse func()) {
 
 	reader, info, readerError := createReaderFromFileName(fileName)
 	if readerError != nil {
-		NotifierSendError(self.server.notifier, readerError)
+		NotifierSendError(self.notifier, readerError)
 		return
 	}
 
 	if self.webSocket != nil {
 		content, readError := io.ReadAll(reader)
 		if readError != nil {
-			NotifierSendError(self.server.notifier, readError)
+			NotifierSendError(self.notifier, readError)
 			return
 		}
 		writeError := self.webSocket.WriteMessage(websocket.TextMessage, content)
 		if writeError != nil {
-			NotifierSendError(self.server.notifier, writeError)
+			NotifierSendError(self.notifier, writeError)
 		}
 		return
 	}
@@ -1180,7 +1602,7 @@ func SendFileOrIndexOrElse(self *Response, orElse func()) {
 	if "" != self.eventName {
 		content, readError := io.ReadAll(reader)
 		if readError != nil {
-			NotifierSendError(self.server.notifier, readError)
+			NotifierSendError(self.notifier, readError)
 			return
 		}
 		sendEventContent(self, content)
@@ -1209,19 +1631,19 @@ func SendFileOrElse(self *Response, orElse func()) {
 
 	reader, info, readerError := createReaderFromFileName(fileName)
 	if readerError != nil {
-		NotifierSendError(self.server.notifier, readerError)
+		NotifierSendError(self.notifier, readerError)
 		return
 	}
 
 	if self.webSocket != nil {
 		content, readError := io.ReadAll(reader)
 		if readError != nil {
-			NotifierSendError(self.server.notifier, readError)
+			NotifierSendError(self.notifier, readError)
 			return
 		}
 		writeError := self.webSocket.WriteMessage(websocket.TextMessage, content)
 		if writeError != nil {
-			NotifierSendError(self.server.notifier, writeError)
+			NotifierSendError(self.notifier, writeError)
 		}
 		return
 	}
@@ -1229,7 +1651,7 @@ func SendFileOrElse(self *Response, orElse func()) {
 	if "" != self.eventName {
 		content, readError := io.ReadAll(reader)
 		if readError != nil {
-			NotifierSendError(self.server.notifier, readError)
+			NotifierSendError(self.notifier, readError)
 			return
 		}
 		sendEventContent(self, content)
@@ -1302,6 +1724,8 @@ func SendServerSentEventsUpgrade(
 	callback func(event func(eventName string)),
 ) {
 	self.eventName = "message"
+	MetricsGaugeAdd(self.server.metrics, "frizzante_server_sent_events_active", nil, 1)
+	defer MetricsGaugeAdd(self.server.metrics, "frizzante_server_sent_events_active", nil, -1)
 	callback(func(eventName string) {
 		self.eventName = eventName
 	})
@@ -1312,18 +1736,20 @@ func SendWebSocketUpgrade(self *Response, callback func()) {
 	request := self.request
 	conn, upgradeError := self.server.webSocketUpgrader.Upgrade(*self.writer, request.httpRequest, nil)
 	if upgradeError != nil {
-		NotifierSendError(request.server.notifier, upgradeError)
+		NotifierSendError(request.notifier, upgradeError)
 		return
 	}
 	defer func(conn *websocket.Conn) {
 		closeError := conn.Close()
 		if closeError != nil {
-			NotifierSendError(request.server.notifier, closeError)
+			NotifierSendError(request.notifier, closeError)
 		}
 	}(conn)
 	self.webSocket = conn
 	request.webSocketConn = conn
 	self.lockedStatusAndHeader = true
+	MetricsGaugeAdd(self.server.metrics, "frizzante_web_sockets_active", nil, 1)
+	defer MetricsGaugeAdd(self.server.metrics, "frizzante_web_sockets_active", nil, -1)
 	callback()
 }
 
@@ -1331,7 +1757,7 @@ func SendWebSocketUpgrade(self *Response, callback func()) {
 func SendPage(self *Response, page *Page) {
 	content, compileError := PageCompile(page)
 	if nil != compileError {
-		NotifierSendError(self.server.notifier, compileError)
+		NotifierSendError(self.notifier, compileError)
 		return
 	}
 
@@ -1343,27 +1769,62 @@ func SendPage(self *Response, page *Page) {
 }
 
 // ServerWithSessionOperator sets the session operator,
-// which is a function that provides the four main
+// which is a function that provides the main
 // operations used by the server to manage any session,
-// get, set, unset and destroy.
+// get, set, unset, validate and destroy.
 //
-// Get must retrieve data from the session store.
+// Get must retrieve data from the session store,
+// or return the given default value, without storing it, when the store has no such property.
 //
 // Set must create a new property to the session store or update an existing one.
 //
 // Unset must remove a property from the session store.
 //
+// Validate must check if the session store is still valid,
+// invalid sessions are destroyed.
+// Clients presenting a session id the server doesn't know about,
+// like after a restart, are given a new session,
+// see ServerWithSessionListingOperator.
+//
 // Destroy must destroy the whole session, store included.
 //
-// In this context, "store", is any type dataata storage,
+// In this context, "store", is any type data storage,
 // it could be a file written to disk, a database, Ram,
 // it doesn't matter.
 //
 // The only thing that matters is a consistent
-// implementation of the four operations.
+// implementation of all operations.
 func ServerWithSessionOperator(
 	self *Server,
 	sessionOperator SessionOperator,
+) {
+	self.sessionOperator = func(
+		sessionId string,
+		withGetter func(get SessionGetter),
+		withSetter func(set SessionSetter),
+		withUnsetter func(unset SessionUnsetter),
+		withValidator func(validate SessionValidator),
+		withDestroyer func(destroy SessionDestroyer),
+		withLister func(list SessionLister),
+	) {
+		sessionOperator(sessionId, withGetter, withSetter, withUnsetter, withValidator, withDestroyer)
+	}
+}
+
+// ServerWithSessionListingOperator sets a session operator which can also list the data of a session,
+// see ServerWithSessionOperator.
+//
+// List must retrieve a copy of all properties of the session store,
+// it's used to migrate data when the session is regenerated, see SessionRegenerate,
+// and to tell empty sessions apart when adopting them.
+//
+// Clients presenting a session id the server doesn't know about,
+// like after a restart, are given their session back only if it's valid and not empty.
+//
+// Sessions of operators that can't list their data are regenerated empty.
+func ServerWithSessionListingOperator(
+	self *Server,
+	sessionOperator SessionListingOperator,
 ) {
 	self.sessionOperator = sessionOperator
 }
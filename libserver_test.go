@@ -1,6 +1,8 @@
 package frizzante
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -54,6 +56,53 @@ func TestServerWithWriteTimeout(test *testing.T) {
 	}
 }
 
+func TestServerWithRequestTimeout(test *testing.T) {
+	server := ServerCreate()
+	expected := 5 * time.Second
+	ServerWithRequestTimeout(server, expected)
+	actual := server.requestTimeout
+	if actual != expected {
+		test.Fatalf("server was expected to have request timeout '%d', received '%d' instead", expected, actual)
+	}
+}
+
+func TestServerWithRouteTimeout(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithRouteTimeout(server, "GET /slow", 100*time.Millisecond)
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /slow")
+		serve(func(request *Request, response *Response) {
+			ctx := ReceiveContext(request)
+			select {
+			case <-ctx.Done():
+				SendEcho(response, ctx.Err().Error())
+			case <-time.After(5 * time.Second):
+				SendEcho(response, "done")
+			}
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	expected := context.DeadlineExceeded.Error()
+	actual, getError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/slow", port), nil)
+	if getError != nil {
+		test.Fatal(getError)
+	}
+
+	if actual != expected {
+		test.Fatalf("server was expected to respond with '%s', received '%s' instead", expected, actual)
+	}
+}
+
 func TestServerWithMaxHeaderBytes(test *testing.T) {
 	server := ServerCreate()
 	expected := 1 * MB
@@ -252,3 +301,122 @@ func TestSendHeader(test *testing.T) {
 		test.Fatalf("server was expected to respond with header content type '%s', received '%s' intead", expected, actual)
 	}
 }
+
+func TestServerWithNotFound(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithNotFound(server, "", func(_ *Request, _ *Response, p *Page) {
+		PageWithData(p, "hint", "try again")
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /users")
+		serve(func(_ *Request, response *Response) {
+			SendEcho(response, "users")
+		})
+	})
+	ServerWithIndex(server, func(
+		route func(path string, page string),
+		show func(showFunction func(req *Request, res *Response, p *Page)),
+		action func(actionFunction func(req *Request, res *Response, p *Page)),
+	) {
+		route("/", "welcome")
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	for _, path := range []string{"/missing", "/users/missing"} {
+		request, requestError := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+		if requestError != nil {
+			test.Fatal(requestError)
+		}
+		request.Header.Set("Accept", "application/json")
+
+		response, doError := http.DefaultClient.Do(request)
+		if doError != nil {
+			test.Fatal(doError)
+		}
+
+		if http.StatusNotFound != response.StatusCode {
+			test.Fatalf("server was expected to respond to '%s' with status code '%d', received '%d' instead", path, http.StatusNotFound, response.StatusCode)
+		}
+
+		var data map[string]any
+		decodeError := json.NewDecoder(response.Body).Decode(&data)
+		_ = response.Body.Close()
+		if decodeError != nil {
+			test.Fatal(decodeError)
+		}
+
+		if "try again" != data["hint"] || float64(http.StatusNotFound) != data["status"] {
+			test.Fatalf("server was expected to respond to '%s' with the not found page data, received '%v' instead", path, data)
+		}
+	}
+}
+
+func TestServerWithMethodNotAllowed(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /users/{id}")
+		route("DELETE /users/{id}")
+		serve(func(_ *Request, response *Response) {
+			SendEcho(response, "user")
+		})
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /")
+		serve(func(_ *Request, response *Response) {
+			SendEcho(response, "index")
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	response, postError := http.Post(fmt.Sprintf("http://127.0.0.1:%d/users/1", port), "text/plain", nil)
+	if postError != nil {
+		test.Fatal(postError)
+	}
+	defer response.Body.Close()
+
+	if http.StatusMethodNotAllowed != response.StatusCode {
+		test.Fatalf("server was expected to respond with status code '%d', received '%d' instead", http.StatusMethodNotAllowed, response.StatusCode)
+	}
+
+	expected := "DELETE, GET, HEAD"
+	actual := response.Header.Get("Allow")
+	if actual != expected {
+		test.Fatalf("server was expected to respond with header allow '%s', received '%s' instead", expected, actual)
+	}
+
+	request, requestError := http.NewRequest("DELETE", fmt.Sprintf("http://127.0.0.1:%d/does-not-exist", port), nil)
+	if requestError != nil {
+		test.Fatal(requestError)
+	}
+
+	unknownResponse, deleteError := http.DefaultClient.Do(request)
+	if deleteError != nil {
+		test.Fatal(deleteError)
+	}
+	defer unknownResponse.Body.Close()
+
+	if http.StatusNotFound != unknownResponse.StatusCode {
+		test.Fatalf("server was expected to respond to unknown paths with status code '%d', received '%d' instead", http.StatusNotFound, unknownResponse.StatusCode)
+	}
+}
@@ -1,19 +1,387 @@
 package frizzante
 
 import (
+	"fmt"
 	uuid "github.com/nu7hatch/gouuid"
 	"net/http"
+	"sync"
+	"time"
 )
 
-var sessions = map[string]*Session{}
+// sessionMissing is passed as default value to detect missing properties.
+type sessionMissing struct{}
 
 type Session struct {
-	id       string
-	get      func(key string, defaultValue any) (value any)
-	set      func(key string, value any)
-	unset    func(key string)
-	validate func() (valid bool)
-	destroy  func()
+	id             string
+	manager        *sessionManager
+	createdAt      time.Time
+	lastActivityAt time.Time
+	get            func(key string, defaultValue any) (value any)
+	set            func(key string, value any)
+	unset          func(key string)
+	validate       func() (valid bool)
+	destroy        func()
+	list           func() (data map[string]any)
+}
+
+// sessionManager keeps track of the sessions of a server.
+type sessionManager struct {
+	lock     sync.Mutex
+	sessions map[string]*Session
+}
+
+// sessionManagerCreate creates a session manager.
+func sessionManagerCreate() *sessionManager {
+	return &sessionManager{
+		sessions: map[string]*Session{},
+	}
+}
+
+// sessionManagerAdd adds a session to the manager.
+//
+// If the manager already has a session with the same id, that session is returned instead.
+func sessionManagerAdd(self *sessionManager, session *Session) (added *Session, count int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if existing, exists := self.sessions[session.id]; exists {
+		return existing, len(self.sessions)
+	}
+	session.manager = self
+	self.sessions[session.id] = session
+	return session, len(self.sessions)
+}
+
+// sessionManagerFind finds a session by id.
+func sessionManagerFind(self *sessionManager, sessionId string) (session *Session, exists bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	session, exists = self.sessions[sessionId]
+	return
+}
+
+// sessionManagerRemove removes a session from the manager.
+//
+// It returns false if the session had already been removed,
+// in which case the caller must not destroy it again.
+func sessionManagerRemove(self *sessionManager, session *Session) (removed bool, count int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	current, exists := self.sessions[session.id]
+	removed = exists && current == session
+	if removed {
+		delete(self.sessions, session.id)
+	}
+	count = len(self.sessions)
+	return
+}
+
+// sessionManagerList lists all sessions of the manager.
+func sessionManagerList(self *sessionManager) []*Session {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	sessions := make([]*Session, 0, len(self.sessions))
+	for _, session := range self.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// sessionTouch marks the session as active.
+func sessionTouch(self *Session) {
+	self.manager.lock.Lock()
+	defer self.manager.lock.Unlock()
+	self.lastActivityAt = time.Now()
+}
+
+// sessionExpired checks if the session exceeded the idle or absolute lifetime of the server.
+func sessionExpired(server *Server, session *Session, now time.Time) bool {
+	session.manager.lock.Lock()
+	defer session.manager.lock.Unlock()
+	return sessionLifetimeExceeded(server, session.createdAt, session.lastActivityAt, now)
+}
+
+// sessionLifetimeExceeded checks if a session created and last active at the given times
+// exceeded the idle or absolute lifetime of the server.
+func sessionLifetimeExceeded(server *Server, createdAt time.Time, lastActivityAt time.Time, now time.Time) bool {
+	if server.sessionIdleLifetime > 0 && now.Sub(lastActivityAt) >= server.sessionIdleLifetime {
+		return true
+	}
+
+	if server.sessionMaxLifetime > 0 && now.Sub(createdAt) >= server.sessionMaxLifetime {
+		return true
+	}
+
+	return false
+}
+
+// sessionValid checks if the session is still valid, according to both the server and the session operator.
+func sessionValid(server *Server, session *Session) bool {
+	return !sessionExpired(server, session, time.Now()) && session.validate()
+}
+
+// sessionRemove removes the session from the server and destroys it.
+func sessionRemove(server *Server, session *Session) {
+	removed, count := sessionManagerRemove(server.sessionManager, session)
+	if !removed {
+		return
+	}
+
+	session.destroy()
+	MetricsGaugeSet(server.metrics, "frizzante_sessions_active", nil, float64(count))
+}
+
+// ServerWithSessionIdleLifetime sets for how long a session can stay unused before it expires,
+// 30 minutes by default.
+//
+// A session is used whenever it's started with SessionStart.
+//
+// Zero disables the idle lifetime.
+func ServerWithSessionIdleLifetime(self *Server, idleLifetime time.Duration) {
+	self.sessionIdleLifetime = idleLifetime
+}
+
+// ServerWithSessionAbsoluteLifetime sets for how long a session can exist before it expires,
+// regardless of its activity.
+//
+// Zero, the default, disables the absolute lifetime.
+func ServerWithSessionAbsoluteLifetime(self *Server, absoluteLifetime time.Duration) {
+	self.sessionMaxLifetime = absoluteLifetime
+}
+
+// ServerWithSessionSweepInterval sets how often expired sessions are swept, 1 minute by default.
+func ServerWithSessionSweepInterval(self *Server, sweepInterval time.Duration) {
+	self.sessionSweepInterval = sweepInterval
+}
+
+// ServerWithSessionCleaner sets a function invoked after each sweep,
+// which removes expired sessions the session operator persisted
+// but the server doesn't know about, like sessions of a previous instance of the server.
+//
+// See SessionOperatorFileCreate and SessionOperatorSqlCreate.
+func ServerWithSessionCleaner(self *Server, cleaner func()) {
+	self.sessionCleaner = cleaner
+}
+
+// sessionSweep destroys all sessions that are no longer valid.
+func sessionSweep(server *Server) {
+	for _, session := range sessionManagerList(server.sessionManager) {
+		if !sessionValid(server, session) {
+			sessionRemove(server, session)
+		}
+	}
+
+	if nil != server.sessionCleaner {
+		server.sessionCleaner()
+	}
+}
+
+// sessionPersistedExpired checks if a persisted session exceeded the idle or absolute lifetime of the server,
+// given when it was created and when it was last written to.
+//
+// Sessions the server knows about never expire this way, they're validated by the server itself,
+// which also keeps track of reads.
+func sessionPersistedExpired(server *Server, sessionId string, createdAt time.Time, updatedAt time.Time) bool {
+	if _, managed := sessionManagerFind(server.sessionManager, sessionId); managed {
+		return false
+	}
+
+	return sessionLifetimeExceeded(server, createdAt, updatedAt, time.Now())
+}
+
+// sessionSweeperStart sweeps sessions periodically until stop is closed.
+func sessionSweeperStart(server *Server, stop chan struct{}) {
+	if server.sessionSweepInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(server.sessionSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			sessionSweep(server)
+		}
+	}
+}
+
+// SessionOperatorMemoryCreate creates a session operator that keeps sessions in memory.
+//
+// This is the default session operator.
+//
+// The store of a session is created when the session is first written to.
+func SessionOperatorMemoryCreate() SessionListingOperator {
+	var lock sync.Mutex
+	memory := map[string]map[string]any{}
+
+	return func(
+		sessionId string,
+		withGetter func(get SessionGetter),
+		withSetter func(set SessionSetter),
+		withUnsetter func(unset SessionUnsetter),
+		withValidator func(validate SessionValidator),
+		withDestroyer func(destroy SessionDestroyer),
+		withLister func(list SessionLister),
+	) {
+		// store finds the store of the session, creating it if requested.
+		//
+		// It must be invoked while holding the lock.
+		store := func(create bool) map[string]any {
+			data, exists := memory[sessionId]
+			if !exists && create {
+				data = map[string]any{}
+				memory[sessionId] = data
+			}
+			return data
+		}
+
+		withGetter(func(key string, defaultValue any) (value any) {
+			lock.Lock()
+			defer lock.Unlock()
+			sessionItem, ok := store(false)[key]
+			if !ok {
+				value = defaultValue
+				return
+			}
+
+			value = sessionItem
+			return
+		})
+
+		withSetter(func(key string, value any) {
+			lock.Lock()
+			defer lock.Unlock()
+			store(true)[key] = value
+		})
+
+		withUnsetter(func(key string) {
+			lock.Lock()
+			defer lock.Unlock()
+			delete(store(false), key)
+		})
+
+		withValidator(func() (valid bool) {
+			// Sessions in memory expire along with the server.
+			return true
+		})
+
+		withDestroyer(func() {
+			lock.Lock()
+			defer lock.Unlock()
+			delete(memory, sessionId)
+		})
+
+		withLister(func() (list map[string]any) {
+			lock.Lock()
+			defer lock.Unlock()
+			list = map[string]any{}
+			for key, value := range store(false) {
+				list[key] = value
+			}
+			return
+		})
+	}
+}
+
+// ServerWithSessionCookie sets the attributes of the session cookie.
+//
+// The value of the template is ignored, all other attributes are sent as they are,
+// which includes the name, "session-id" by default.
+//
+// By default, the session cookie has Path=/, HttpOnly and SameSite=Lax.
+func ServerWithSessionCookie(self *Server, cookie *http.Cookie) {
+	template := *cookie
+	template.Value = ""
+	if "" == template.Name {
+		template.Name = "session-id"
+	}
+	self.sessionCookie = &template
+}
+
+// sessionSendCookie sends the session cookie with the given session id,
+// replacing any session cookie previously sent with the response.
+func sessionSendCookie(request *Request, response *Response, sessionId string) {
+	cookie := *request.server.sessionCookie
+	cookie.Value = sessionId
+	sendCookieWithdraw(response, cookie.Name)
+	SendCookie(response, &cookie)
+}
+
+// sessionCreate creates a session with the given id using the session operator of the server.
+func sessionCreate(request *Request, sessionId string) *Session {
+	session := sessionCreateWithOperator(request.server.sessionOperator, sessionId)
+	session.manager = request.server.sessionManager
+	return session
+}
+
+// sessionCreateWithOperator creates a session with the given id using the given session operator.
+func sessionCreateWithOperator(operator SessionListingOperator, sessionId string) *Session {
+	session := &Session{
+		id:             sessionId,
+		createdAt:      time.Now(),
+		lastActivityAt: time.Now(),
+	}
+	operator(
+		sessionId,
+		func(get func(key string, defaultValue any) (value any)) {
+			session.get = get
+		},
+		func(set func(key string, value any)) {
+			session.set = set
+		},
+		func(unset func(key string)) {
+			session.unset = unset
+		},
+		func(validate func() (valid bool)) {
+			session.validate = validate
+		},
+		func(destroy func()) {
+			session.destroy = destroy
+		},
+		func(list func() (data map[string]any)) {
+			session.list = list
+		},
+	)
+	return session
+}
+
+// sessionAdopt adopts a session the manager doesn't know about, but the session operator does,
+// like a session persisted by a previous instance of the server.
+func sessionAdopt(request *Request, sessionId string) (session *Session, adopted bool) {
+	_, parseError := uuid.ParseHex(sessionId)
+	if parseError != nil {
+		return nil, false
+	}
+
+	session = sessionCreate(request, sessionId)
+	if nil == session.list || !session.validate() || 0 == len(session.list()) {
+		// Only sessions the operator can show to exist and to hold data are adopted,
+		// anything else would let anyone choose the id of a new session.
+		return nil, false
+	}
+
+	session, count := sessionManagerAdd(request.server.sessionManager, session)
+	MetricsGaugeSet(request.server.metrics, "frizzante_sessions_active", nil, float64(count))
+	return session, true
+}
+
+// sessionCreateFresh creates a session with a new id.
+func sessionCreateFresh(request *Request, response *Response) *Session {
+	uuidV4, sessionIdError := uuid.NewV4()
+	if sessionIdError != nil {
+		NotifierSendError(request.notifier, sessionIdError)
+	}
+
+	session := sessionCreate(request, uuidV4.String())
+	sessionSendCookie(request, response, session.id)
+	_, count := sessionManagerAdd(request.server.sessionManager, session)
+	request.session = session
+	request.sessionId = session.id
+	MetricsCounterAdd(request.server.metrics, "frizzante_sessions_created_total", nil, 1)
+	MetricsGaugeSet(request.server.metrics, "frizzante_sessions_active", nil, float64(count))
+	return session
 }
 
 // SessionStart first tries to retrieve the client session, then,
@@ -22,140 +390,159 @@ type Session struct {
 //
 // It always returns three functions, get, set and unset.
 //
-// Use get to retrieve a property from the session.
+// Use get to retrieve a property from the session,
+// or defaultValue if the session has no such property.
 //
 // Use set to create a new property or update an existing one to the session.
 //
 // Use unset to remove a property from the session.
+//
+// See SessionGet, SessionSet and SessionUnset for typed alternatives.
 func SessionStart(request *Request, response *Response) (
 	get func(key string, defaultValue any) (value any),
 	set func(key string, value any),
 	unset func(key string),
 ) {
+	session := request.session
+	if nil == session {
+		session = sessionStart(request, response)
+		flashConsume(request, session)
+	}
 
-	var sessionIdCookie *http.Cookie
-	sessionIdCookies := request.httpRequest.CookiesNamed("session-id")
-	sessionIdCookiesLen := len(sessionIdCookies)
-
-	if 0 == sessionIdCookiesLen {
-		uuidV4, sessionIdError := uuid.NewV4()
-
-		if sessionIdError != nil {
-			NotifierSendError(request.server.notifier, sessionIdError)
-		}
+	get = session.get
+	set = session.set
+	unset = session.unset
+	return
+}
 
-		sessionId := uuidV4.String()
-
-		var sessionGetter func(key string, defaultValue any) (value any)
-		var sessionSetter func(key string, value any)
-		var sessionUnsetter func(key string)
-		var sessionValidator func() (valid bool)
-		var sessionDestroyer func()
-
-		request.server.sessionOperator(
-			sessionId,
-			func(get func(key string, defaultValue any) (value any)) {
-				sessionGetter = get
-			},
-			func(set func(key string, value any)) {
-				sessionSetter = set
-			},
-			func(unset func(key string)) {
-				sessionUnsetter = unset
-			},
-			func(validate func() (valid bool)) {
-				sessionValidator = validate
-			},
-			func(destroy func()) {
-				sessionDestroyer = destroy
-			},
-		)
-
-		freshSession := &Session{
-			id:       sessionId,
-			get:      sessionGetter,
-			set:      sessionSetter,
-			unset:    sessionUnsetter,
-			validate: sessionValidator,
-			destroy:  sessionDestroyer,
-		}
+// sessionStore keeps the sessions of a server.
+//
+// Sessions are kept by the server through the session operator by default,
+// see ServerWithSessionOperator, but they can also be kept by the client,
+// see ServerWithSessionCookieStore.
+type sessionStore struct {
+	// start retrieves the client session or creates a new one.
+	start func(request *Request, response *Response) *Session
+	// regenerate destroys the previous client session and creates a new empty one.
+	regenerate func(request *Request, response *Response, previous *Session) *Session
+	// find retrieves the id of the client session without starting it.
+	find func(request *Request) (sessionId string, found bool)
+}
 
-		SendCookie(response, "session-id", freshSession.id)
-		sessions[freshSession.id] = freshSession
-		get = sessionGetter
-		set = sessionSetter
-		unset = sessionUnsetter
-		return
+// sessionStoreOperatorCreate creates a session store which keeps sessions
+// through the session operator of the server.
+func sessionStoreOperatorCreate() *sessionStore {
+	return &sessionStore{
+		start: sessionOperatorStart,
+		regenerate: func(request *Request, response *Response, previous *Session) *Session {
+			sessionRemove(request.server, previous)
+			return sessionCreateFresh(request, response)
+		},
+		find: func(request *Request) (sessionId string, found bool) {
+			sessionIdCookies := request.httpRequest.CookiesNamed(request.server.sessionCookie.Name)
+			if len(sessionIdCookies) > 0 && "" != sessionIdCookies[0].Value {
+				return sessionIdCookies[0].Value, true
+			}
+			return "", false
+		},
 	}
+}
 
-	var sessionExists bool
+// sessionStart retrieves the client session or creates a new one.
+func sessionStart(request *Request, response *Response) *Session {
+	return request.server.sessionStore.start(request, response)
+}
+
+// sessionOperatorStart retrieves the client session through the session operator or creates a new one.
+func sessionOperatorStart(request *Request, response *Response) *Session {
 	var session *Session
+	var sessionExists bool
 
-	for _, cookie := range sessionIdCookies {
-		session, sessionExists = sessions[cookie.Value]
+	for _, cookie := range request.httpRequest.CookiesNamed(request.server.sessionCookie.Name) {
+		session, sessionExists = sessionManagerFind(request.server.sessionManager, cookie.Value)
+		if !sessionExists {
+			session, sessionExists = sessionAdopt(request, cookie.Value)
+		}
 		if sessionExists {
-			sessionIdCookie = cookie
 			break
 		}
 	}
 
+	if sessionExists && !sessionValid(request.server, session) {
+		sessionRemove(request.server, session)
+		sessionExists = false
+	}
+
 	if !sessionExists {
-		uuidV4, sessionIdError := uuid.NewV4()
-		if sessionIdError != nil {
-			NotifierSendError(request.server.notifier, sessionIdError)
-		}
-		sessionId := uuidV4.String()
-
-		var sessionGetter func(key string, defaultValue any) (value any)
-		var sessionSetter func(key string, value any)
-		var sessionUnsetter func(key string)
-		var sessionValidator func() (valid bool)
-		var sessionDestroyer func()
-
-		request.server.sessionOperator(
-			sessionId,
-			func(get func(key string, defaultValue any) (value any)) {
-				sessionGetter = get
-			},
-			func(set func(key string, value any)) {
-				sessionSetter = set
-			},
-			func(unset func(key string)) {
-				sessionUnsetter = unset
-			},
-			func(validate func() (valid bool)) {
-				sessionValidator = validate
-			},
-			func(destroy func()) {
-				sessionDestroyer = destroy
-			},
-		)
-
-		freshSession := &Session{
-			id:       sessionId,
-			get:      sessionGetter,
-			set:      sessionSetter,
-			unset:    sessionUnsetter,
-			validate: sessionValidator,
-			destroy:  sessionDestroyer,
-		}
+		return sessionCreateFresh(request, response)
+	}
 
-		SendCookie(response, "session-id", freshSession.id)
-		sessions[freshSession.id] = freshSession
-		get = sessionGetter
-		set = sessionSetter
-		unset = sessionUnsetter
-		return
+	sessionTouch(session)
+	sessionSendCookie(request, response, session.id)
+	request.session = session
+	request.sessionId = session.id
+	return session
+}
+
+// SessionGet retrieves a property from the client session, starting the session if needed.
+//
+// It returns defaultValue if the session has no such property,
+// or an error if the property is not of type T.
+func SessionGet[T any](request *Request, response *Response, key string, defaultValue T) (value T, err error) {
+	get, _, _ := SessionStart(request, response)
+	item := get(key, sessionMissing{})
+	if _, missing := item.(sessionMissing); missing {
+		return defaultValue, nil
 	}
 
-	if !session.validate() {
-		delete(sessions, sessionIdCookie.Value)
-		session.destroy()
-		SessionStart(request, response)
-		return
+	value, ok := item.(T)
+	if !ok {
+		return defaultValue, fmt.Errorf("session property `%s` is of type %T, not %T", key, item, defaultValue)
+	}
+
+	return value, nil
+}
+
+// SessionSet creates a new property or updates an existing one to the client session, starting the session if needed.
+func SessionSet(request *Request, response *Response, key string, value any) {
+	_, set, _ := SessionStart(request, response)
+	set(key, value)
+}
+
+// SessionUnset removes a property from the client session, starting the session if needed.
+func SessionUnset(request *Request, response *Response, key string) {
+	_, _, unset := SessionStart(request, response)
+	unset(key)
+}
+
+// SessionRegenerate issues a new id for the client session, migrating all of its data
+// and destroying the session associated with the old id.
+//
+// Invoke it whenever the privileges of the client change, like after login,
+// in order to prevent session fixation.
+//
+// The csrf token is not migrated, a new one is created when needed.
+func SessionRegenerate(request *Request, response *Response) (
+	get func(key string, defaultValue any) (value any),
+	set func(key string, value any),
+	unset func(key string),
+) {
+	SessionStart(request, response)
+
+	previous := request.session
+	data := map[string]any{}
+	if nil != previous.list {
+		data = previous.list()
+	}
+
+	session := request.server.sessionStore.regenerate(request, response, previous)
+	for key, value := range data {
+		if csrfSessionKey == key {
+			continue
+		}
+		session.set(key, value)
 	}
 
-	SendCookie(response, "session-id", session.id)
 	get = session.get
 	set = session.set
 	unset = session.unset
@@ -164,5 +551,14 @@ func SessionStart(request *Request, response *Response) (
 
 // SessionDestroy destroys the session.
 func SessionDestroy(self *Session) {
-	self.destroy()
+	if nil == self.manager {
+		// The session is not kept by the server, like sessions stored in cookies.
+		self.destroy()
+		return
+	}
+
+	removed, _ := sessionManagerRemove(self.manager, self)
+	if removed {
+		self.destroy()
+	}
 }
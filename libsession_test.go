@@ -90,3 +90,296 @@ func TestSessionStart(test *testing.T) {
 		test.Fatal(fmt.Sprintf("Message was expected to be `%s`, received `%s` instead.", expected2, actual2))
 	}
 }
+
+func TestSessionRegenerate(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithSessionCookie(server, &http.Cookie{
+		Name:     "sid",
+		Path:     "/",
+		Domain:   "127.0.0.1",
+		MaxAge:   3600,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /cart")
+		serve(func(request *Request, response *Response) {
+			_, set, _ := SessionStart(request, response)
+			set("cart", "apples")
+			SendEcho(response, "")
+		})
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /login")
+		serve(func(request *Request, response *Response) {
+			get, _, _ := SessionRegenerate(request, response)
+			SendEcho(response, get("cart", "").(string))
+		})
+	})
+
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	send := func(path string, sessionId string) (*http.Response, string) {
+		request, requestError := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+		if requestError != nil {
+			test.Fatal(requestError)
+		}
+		if "" != sessionId {
+			request.AddCookie(&http.Cookie{Name: "sid", Value: sessionId})
+		}
+		response, doError := http.DefaultClient.Do(request)
+		if doError != nil {
+			test.Fatal(doError)
+		}
+		defer response.Body.Close()
+		content, readError := io.ReadAll(response.Body)
+		if readError != nil {
+			test.Fatal(readError)
+		}
+		return response, string(content)
+	}
+
+	response, _ := send("/cart", "")
+	setCookies := response.Header.Values("Set-Cookie")
+	if 1 != len(setCookies) {
+		test.Fatalf("response was expected to set 1 cookie, received %d instead", len(setCookies))
+	}
+
+	cookies := response.Cookies()
+	cookie := cookies[0]
+	if "sid" != cookie.Name || "127.0.0.1" != cookie.Domain || 3600 != cookie.MaxAge || !cookie.Secure || !cookie.HttpOnly || http.SameSiteStrictMode != cookie.SameSite {
+		test.Fatalf("session cookie was expected to have the configured attributes, received '%s' instead", setCookies[0])
+	}
+
+	previousId := cookie.Value
+	response, content := send("/login", previousId)
+	if "apples" != content {
+		test.Fatalf("regenerated session was expected to keep its data, received '%s' instead", content)
+	}
+
+	setCookies = response.Header.Values("Set-Cookie")
+	if 1 != len(setCookies) {
+		test.Fatalf("regeneration was expected to set exactly 1 cookie, received %v instead", setCookies)
+	}
+
+	currentId := response.Cookies()[0].Value
+	if previousId == currentId {
+		test.Fatal("regenerated session was expected to have a new id")
+	}
+
+	if _, exists := sessionManagerFind(server.sessionManager, previousId); exists {
+		test.Fatal("previous session was expected to be destroyed")
+	}
+
+	_, content = send("/login", currentId)
+	if "apples" != content {
+		test.Fatalf("new session id was expected to be valid, received '%s' instead", content)
+	}
+}
+
+func TestSessionSweep(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithSessionIdleLifetime(server, 500*time.Millisecond)
+	ServerWithSessionAbsoluteLifetime(server, 2*time.Second)
+	ServerWithSessionSweepInterval(server, 100*time.Millisecond)
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /")
+		serve(func(request *Request, response *Response) {
+			_, set, _ := SessionStart(request, response)
+			set("name", "world")
+			SendEcho(response, request.sessionId)
+		})
+	})
+
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	send := func(sessionId string) string {
+		request, _ := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/", port), nil)
+		if "" != sessionId {
+			request.AddCookie(&http.Cookie{Name: "session-id", Value: sessionId})
+		}
+		response, responseError := http.DefaultClient.Do(request)
+		if responseError != nil {
+			test.Fatal(responseError)
+		}
+		body, _ := io.ReadAll(response.Body)
+		return string(body)
+	}
+
+	idleId := send("")
+	activeId := send("")
+	session, _ := sessionManagerFind(server.sessionManager, idleId)
+
+	for range 4 {
+		time.Sleep(300 * time.Millisecond)
+		if activeId != send(activeId) {
+			test.Fatal("active session was expected to be kept alive")
+		}
+	}
+
+	if _, exists := sessionManagerFind(server.sessionManager, idleId); exists {
+		test.Fatal("idle session was expected to be swept")
+	}
+
+	if 0 != len(session.list()) {
+		test.Fatal("idle session was expected to be destroyed by its operator")
+	}
+
+	time.Sleep(1 * time.Second)
+
+	if _, exists := sessionManagerFind(server.sessionManager, activeId); exists {
+		test.Fatal("active session was expected to be swept after its absolute lifetime")
+	}
+}
+
+func TestSessionGet(test *testing.T) {
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /")
+		serve(func(request *Request, response *Response) {
+			SessionSet(request, response, "count", 3)
+
+			count, countError := SessionGet(request, response, "count", 0)
+			if countError != nil || 3 != count {
+				test.Errorf("count was expected to be 3, received %d instead", count)
+			}
+
+			_, typeError := SessionGet(request, response, "count", "")
+			if nil == typeError {
+				test.Error("reading count as a string was expected to fail")
+			}
+
+			name, nameError := SessionGet(request, response, "name", "world")
+			if nameError != nil || "world" != name {
+				test.Errorf("name was expected to default to world, received %s instead", name)
+			}
+
+			if _, exists := request.session.list()["name"]; exists {
+				test.Error("default name was not expected to be persisted")
+			}
+
+			SessionUnset(request, response, "count")
+			if _, exists := request.session.list()["count"]; exists {
+				test.Error("count was expected to be removed")
+			}
+
+			SendEcho(response, "")
+		})
+	})
+
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	response, responseError := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", port))
+	if responseError != nil {
+		test.Fatal(responseError)
+	}
+	_ = response.Body.Close()
+}
+
+func TestServerWithSessionOperator(test *testing.T) {
+	stores := map[string]map[string]any{}
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithSessionOperator(server, func(
+		sessionId string,
+		withGetter func(get SessionGetter),
+		withSetter func(set SessionSetter),
+		withUnsetter func(unset SessionUnsetter),
+		withValidator func(validate SessionValidator),
+		withDestroyer func(destroy SessionDestroyer),
+	) {
+		if _, exists := stores[sessionId]; !exists {
+			stores[sessionId] = map[string]any{}
+		}
+		withGetter(func(key string, defaultValue any) (value any) {
+			value, exists := stores[sessionId][key]
+			if !exists {
+				return defaultValue
+			}
+			return value
+		})
+		withSetter(func(key string, value any) { stores[sessionId][key] = value })
+		withUnsetter(func(key string) { delete(stores[sessionId], key) })
+		withValidator(func() (valid bool) { return true })
+		withDestroyer(func() { delete(stores, sessionId) })
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /")
+		serve(func(request *Request, response *Response) {
+			get, set, _ := SessionStart(request, response)
+			set("count", get("count", 0).(int)+1)
+			SendEcho(response, fmt.Sprintf("%d", get("count", 0)))
+		})
+	})
+
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	response, responseError := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", port))
+	if responseError != nil {
+		test.Fatal(responseError)
+	}
+	_ = response.Body.Close()
+
+	sessionId := response.Cookies()[0].Value
+	actual, getError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/", port), map[string]string{
+		"Cookie": fmt.Sprintf("session-id=%s", sessionId),
+	})
+	if getError != nil {
+		test.Fatal(getError)
+	}
+
+	if "2" != actual {
+		test.Fatalf("operators that can't list their data were expected to work, received '%s' instead", actual)
+	}
+
+	chosenId := "bb7c2bc5-a2e8-4a4c-9c5e-b3f0a4ed6f4a"
+	request, _ := http.NewRequest("GET", fmt.Sprintf("http://127.0.0.1:%d/", port), nil)
+	request.AddCookie(&http.Cookie{Name: "session-id", Value: chosenId})
+	response, responseError = http.DefaultClient.Do(request)
+	if responseError != nil {
+		test.Fatal(responseError)
+	}
+	_ = response.Body.Close()
+
+	for _, cookie := range response.Cookies() {
+		if "session-id" == cookie.Name && chosenId == cookie.Value {
+			test.Fatalf("session ids chosen by the client were expected to be replaced, received '%s' instead", cookie.Value)
+		}
+	}
+}
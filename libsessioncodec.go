@@ -0,0 +1,231 @@
+package frizzante
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type SessionCodec struct {
+	name      string
+	marshal   func(value any) ([]byte, error)
+	unmarshal func(content []byte, value any) error
+}
+
+type sessionEnvelope struct {
+	Type  string `json:"type" msgpack:"type"`
+	Value []byte `json:"value" msgpack:"value"`
+}
+
+var sessionTypesLock sync.RWMutex
+var sessionTypes = map[string]reflect.Type{}
+var sessionTypeNames = map[reflect.Type]string{}
+
+func init() {
+	// Gob needs to know generic containers to encode them as interfaces.
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+
+	// These can't fail, gob already knows most of them.
+	SessionRegister[string]("string")
+	SessionRegister[bool]("bool")
+	SessionRegister[int]("int")
+	SessionRegister[int8]("int8")
+	SessionRegister[int16]("int16")
+	SessionRegister[int32]("int32")
+	SessionRegister[int64]("int64")
+	SessionRegister[uint]("uint")
+	SessionRegister[uint8]("uint8")
+	SessionRegister[uint16]("uint16")
+	SessionRegister[uint32]("uint32")
+	SessionRegister[uint64]("uint64")
+	SessionRegister[float32]("float32")
+	SessionRegister[float64]("float64")
+	SessionRegister[[]byte]("[]byte")
+	SessionRegister[[]string]("[]string")
+	SessionRegister[[]int]("[]int")
+	SessionRegister[[]any]("[]any")
+	SessionRegister[map[string]string]("map[string]string")
+	SessionRegister[map[string]any]("map[string]any")
+	SessionRegister[time.Time]("time.Time")
+	SessionRegister[time.Duration]("time.Duration")
+}
+
+// SessionRegister registers a type under a name, so that session values of that type
+// are decoded back to the same type by session codecs.
+//
+// Values of types that are not registered are decoded to whatever the codec chooses,
+// like map[string]any for structs encoded as json.
+//
+// Types are also registered with encoding/gob under the same name,
+// so that gob can encode them within generic containers, like map[string]any.
+//
+// Names are persisted along with the values, they must not change once in use.
+//
+// It returns an error if gob refuses the type, like when it's already registered under another name.
+func SessionRegister[T any](name string) error {
+	sessionTypesLock.Lock()
+	defer sessionTypesLock.Unlock()
+	valueType := reflect.TypeFor[T]()
+	if !sessionGobRegistered(valueType) {
+		registerError := sessionRegisterGob(name, *new(T))
+		if registerError != nil {
+			return registerError
+		}
+	}
+	sessionTypes[name] = valueType
+	sessionTypeNames[valueType] = name
+	return nil
+}
+
+// sessionGobRegistered checks if a type is already registered with encoding/gob,
+// either by gob itself, like basic types and their slices, or by init.
+func sessionGobRegistered(valueType reflect.Type) bool {
+	if reflect.TypeFor[map[string]any]() == valueType || reflect.TypeFor[[]any]() == valueType {
+		return true
+	}
+
+	if reflect.Slice == valueType.Kind() && "" == valueType.Name() {
+		valueType = valueType.Elem()
+	}
+
+	// Predeclared types are the only named types without a package path.
+	if "" != valueType.PkgPath() || "" == valueType.Name() {
+		return false
+	}
+
+	switch valueType.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+
+	return false
+}
+
+// sessionRegisterGob registers a value with encoding/gob under a name,
+// turning the panics of gob into errors.
+func sessionRegisterGob(name string, value any) (err error) {
+	defer func() {
+		recovered := recover()
+		if nil != recovered {
+			err = fmt.Errorf("could not register session type `%s` with gob: %v", name, recovered)
+		}
+	}()
+	gob.RegisterName(name, value)
+	return nil
+}
+
+// SessionCodecJsonCreate creates a session codec encoding sessions as json.
+//
+// This is the default session codec.
+func SessionCodecJsonCreate() *SessionCodec {
+	return &SessionCodec{
+		name:      "json",
+		marshal:   json.Marshal,
+		unmarshal: json.Unmarshal,
+	}
+}
+
+// SessionCodecGobCreate creates a session codec encoding sessions with encoding/gob.
+func SessionCodecGobCreate() *SessionCodec {
+	return &SessionCodec{
+		name: "gob",
+		marshal: func(value any) ([]byte, error) {
+			var buffer bytes.Buffer
+			encodeError := gob.NewEncoder(&buffer).Encode(value)
+			return buffer.Bytes(), encodeError
+		},
+		unmarshal: func(content []byte, value any) error {
+			return gob.NewDecoder(bytes.NewReader(content)).Decode(value)
+		},
+	}
+}
+
+// SessionCodecMsgpackCreate creates a session codec encoding sessions as msgpack.
+func SessionCodecMsgpackCreate() *SessionCodec {
+	return &SessionCodec{
+		name:      "msgpack",
+		marshal:   msgpack.Marshal,
+		unmarshal: msgpack.Unmarshal,
+	}
+}
+
+// ServerWithSessionCodec sets the codec used by session operators that persist sessions,
+// like SessionOperatorFileCreate, SessionOperatorSqlCreate and ServerWithSessionCookieStore.
+func ServerWithSessionCodec(self *Server, codec *SessionCodec) {
+	self.sessionCodec = codec
+}
+
+// SessionCodecEncode encodes the data of a session.
+//
+// Custom session operators that persist sessions should use it,
+// so that values survive persistence as the same types, see SessionRegister.
+func SessionCodecEncode(self *SessionCodec, data map[string]any) ([]byte, error) {
+	envelopes := make(map[string]sessionEnvelope, len(data))
+	for key, value := range data {
+		sessionTypesLock.RLock()
+		name, registered := sessionTypeNames[reflect.TypeOf(value)]
+		sessionTypesLock.RUnlock()
+
+		var content []byte
+		var marshalError error
+		if registered {
+			content, marshalError = self.marshal(value)
+		} else {
+			// Unregistered values are encoded as interfaces,
+			// which lets codecs like gob describe their types.
+			content, marshalError = self.marshal(&value)
+		}
+
+		if marshalError != nil {
+			return nil, fmt.Errorf("could not encode session property `%s` as %s: %w", key, self.name, marshalError)
+		}
+
+		envelopes[key] = sessionEnvelope{Type: name, Value: content}
+	}
+
+	return self.marshal(envelopes)
+}
+
+// SessionCodecDecode decodes the data of a session encoded with SessionCodecEncode.
+func SessionCodecDecode(self *SessionCodec, content []byte) (map[string]any, error) {
+	envelopes := map[string]sessionEnvelope{}
+	unmarshalError := self.unmarshal(content, &envelopes)
+	if unmarshalError != nil {
+		return nil, fmt.Errorf("could not decode session as %s: %w", self.name, unmarshalError)
+	}
+
+	data := make(map[string]any, len(envelopes))
+	for key, envelope := range envelopes {
+		sessionTypesLock.RLock()
+		valueType, registered := sessionTypes[envelope.Type]
+		sessionTypesLock.RUnlock()
+
+		if !registered {
+			var value any
+			unmarshalError = self.unmarshal(envelope.Value, &value)
+			if unmarshalError != nil {
+				return nil, fmt.Errorf("could not decode session property `%s` as %s: %w", key, self.name, unmarshalError)
+			}
+			data[key] = value
+			continue
+		}
+
+		value := reflect.New(valueType)
+		unmarshalError = self.unmarshal(envelope.Value, value.Interface())
+		if unmarshalError != nil {
+			return nil, fmt.Errorf("could not decode session property `%s` as %s: %w", key, self.name, unmarshalError)
+		}
+		data[key] = value.Elem().Interface()
+	}
+
+	return data, nil
+}
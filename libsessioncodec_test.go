@@ -0,0 +1,110 @@
+package frizzante
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type sessionCodecTestUser struct {
+	Name  string
+	Roles []string
+}
+
+type sessionCodecTestPoint struct {
+	X int
+	Y int
+}
+
+type sessionCodecTestProfile struct {
+	Email string
+	Age   int
+}
+
+func TestSessionCodecEncode(test *testing.T) {
+	registerError := SessionRegister[sessionCodecTestUser]("test.user")
+	if registerError != nil {
+		test.Fatal(registerError)
+	}
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := map[string]any{
+		"user":      sessionCodecTestUser{Name: "test", Roles: []string{"admin"}},
+		"count":     3,
+		"ratio":     0.5,
+		"createdAt": createdAt,
+		"fruits":    []string{"apples", "pears"},
+	}
+
+	codecs := []*SessionCodec{SessionCodecJsonCreate(), SessionCodecGobCreate(), SessionCodecMsgpackCreate()}
+	for _, codec := range codecs {
+		content, encodeError := SessionCodecEncode(codec, data)
+		if encodeError != nil {
+			test.Fatalf("%s was expected to encode the session, received error %s instead", codec.name, encodeError)
+		}
+
+		decoded, decodeError := SessionCodecDecode(codec, content)
+		if decodeError != nil {
+			test.Fatalf("%s was expected to decode the session, received error %s instead", codec.name, decodeError)
+		}
+
+		for key, value := range data {
+			if expected, isTime := value.(time.Time); isTime {
+				// Msgpack decodes times to the local time zone.
+				received, receivedIsTime := decoded[key].(time.Time)
+				if !receivedIsTime || !expected.Equal(received) {
+					test.Fatalf("%s was expected to decode %s as %s, received %#v instead", codec.name, key, expected, decoded[key])
+				}
+				continue
+			}
+
+			if !reflect.DeepEqual(value, decoded[key]) {
+				test.Fatalf("%s was expected to decode %s as %#v, received %#v instead", codec.name, key, value, decoded[key])
+			}
+		}
+	}
+
+	content, _ := SessionCodecEncode(SessionCodecJsonCreate(), map[string]any{"point": sessionCodecTestPoint{X: 1, Y: 2}})
+	decoded, decodeError := SessionCodecDecode(SessionCodecJsonCreate(), content)
+	if decodeError != nil {
+		test.Fatal(decodeError)
+	}
+
+	point, _ := decoded["point"].(map[string]any)
+	if nil == point || 1.0 != point["X"] {
+		test.Fatalf("unregistered types were expected to decode as generic values, received %#v instead", decoded["point"])
+	}
+}
+
+func TestSessionRegister(test *testing.T) {
+	registerError := SessionRegister[sessionCodecTestProfile]("test.profile")
+	if registerError != nil {
+		test.Fatal(registerError)
+	}
+
+	registerError = SessionRegister[sessionCodecTestProfile]("test.profile.renamed")
+	if nil == registerError {
+		test.Fatal("types registered under another name were expected to be rejected")
+	}
+
+	profile := sessionCodecTestProfile{Email: "test@example.com", Age: 30}
+	data := map[string]any{
+		"profile":  profile,
+		"settings": map[string]any{"profile": profile},
+	}
+
+	codec := SessionCodecGobCreate()
+	content, encodeError := SessionCodecEncode(codec, data)
+	if encodeError != nil {
+		test.Fatalf("gob was expected to encode registered structs, received error %s instead", encodeError)
+	}
+
+	decoded, decodeError := SessionCodecDecode(codec, content)
+	if decodeError != nil {
+		test.Fatalf("gob was expected to decode registered structs, received error %s instead", decodeError)
+	}
+
+	if !reflect.DeepEqual(data, decoded) {
+		test.Fatalf("gob was expected to decode %#v, received %#v instead", data, decoded)
+	}
+}
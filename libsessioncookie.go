@@ -0,0 +1,384 @@
+package frizzante
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	uuid "github.com/nu7hatch/gouuid"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionCookieChunkSize is the maximum size of the value of each session cookie,
+// which leaves room for the name and attributes within the 4096 bytes browsers accept.
+const sessionCookieChunkSize = 3800
+
+// sessionCookieChunksMax is the maximum number of cookies a session is split into,
+// which keeps the whole session within the request headers most proxies accept.
+const sessionCookieChunksMax = 4
+
+type sessionCookiePayload struct {
+	Id        string         `json:"id"`
+	CreatedAt int64          `json:"created_at"`
+	UpdatedAt int64          `json:"updated_at"`
+	Content   []byte         `json:"data"`
+	Data      map[string]any `json:"-"`
+}
+
+// ServerWithSessionCookieStore stores the whole session in cookies encrypted with AES-GCM,
+// instead of using the session operator, so that any instance of the server can serve any client.
+//
+// Each key must be 16, 24 or 32 bytes long, selecting AES-128, AES-192 or AES-256.
+//
+// Sessions are encrypted with the first key and decrypted with any of them,
+// so keys can be rotated by prepending a new key and removing the oldest one later.
+//
+// Sessions larger than a cookie are split across multiple cookies, named after the session cookie,
+// like "session-id", "session-id-1", "session-id-2" and so on, up to 4 cookies.
+// Changes that would make the session any larger are discarded and sent to the notifier.
+//
+// Cookies are sent only when the session changes,
+// or when the idle lifetime of the session needs to be extended, see ServerWithSessionIdleLifetime and ServerWithSessionAbsoluteLifetime.
+//
+// Session data is encoded with the session codec of the server, see ServerWithSessionCodec.
+//
+// Cookies can only be sent along with the header, so sessions
+// must not be changed after sending any content.
+func ServerWithSessionCookieStore(self *Server, keys ...[]byte) {
+	var aeads []cipher.AEAD
+	for _, key := range keys {
+		block, blockError := aes.NewCipher(key)
+		if blockError != nil {
+			NotifierSendError(self.notifier, blockError)
+			continue
+		}
+
+		aead, aeadError := cipher.NewGCM(block)
+		if aeadError != nil {
+			NotifierSendError(self.notifier, aeadError)
+			continue
+		}
+
+		aeads = append(aeads, aead)
+	}
+
+	if 0 == len(aeads) {
+		self.sessionStore = sessionStoreOperatorCreate()
+		return
+	}
+
+	self.sessionStore = sessionStoreCookieCreate(aeads)
+}
+
+// sessionStoreCookieCreate creates a session store which keeps sessions in the cookies of the client.
+func sessionStoreCookieCreate(keys []cipher.AEAD) *sessionStore {
+	return &sessionStore{
+		start: func(request *Request, response *Response) *Session {
+			payload := sessionCookieRead(request, keys)
+			if nil == payload {
+				return sessionCookieCreateFresh(request, response, keys)
+			}
+
+			return sessionCookieCreate(request, response, keys, payload)
+		},
+		regenerate: func(request *Request, response *Response, previous *Session) *Session {
+			// The cookies of the previous session are replaced by the new ones.
+			return sessionCookieCreateFresh(request, response, keys)
+		},
+		find: func(request *Request) (sessionId string, found bool) {
+			payload := sessionCookieRead(request, keys)
+			if nil == payload {
+				return "", false
+			}
+			return payload.Id, true
+		},
+	}
+}
+
+// sessionCookieChunkName finds the name of the cookie holding the chunk at the given index.
+func sessionCookieChunkName(server *Server, index int) string {
+	if 0 == index {
+		return server.sessionCookie.Name
+	}
+	return fmt.Sprintf("%s-%d", server.sessionCookie.Name, index)
+}
+
+// sessionCookieRead decrypts the session sent by the client.
+//
+// It returns nil if the client sent no session, or if the session can't be decrypted with any key,
+// or if the session expired.
+func sessionCookieRead(request *Request, keys []cipher.AEAD) *sessionCookiePayload {
+	server := request.server
+	var builder strings.Builder
+	for index := range sessionCookieChunksMax {
+		cookie, cookieError := request.httpRequest.Cookie(sessionCookieChunkName(server, index))
+		if cookieError != nil {
+			break
+		}
+		builder.WriteString(cookie.Value)
+	}
+
+	if 0 == builder.Len() {
+		return nil
+	}
+
+	sealed, decodeError := base64.RawURLEncoding.DecodeString(builder.String())
+	if decodeError != nil {
+		return nil
+	}
+
+	for _, aead := range keys {
+		if len(sealed) < aead.NonceSize() {
+			continue
+		}
+
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		content, openError := aead.Open(nil, nonce, ciphertext, []byte(server.sessionCookie.Name))
+		if openError != nil {
+			continue
+		}
+
+		payload := &sessionCookiePayload{}
+		unmarshalError := json.Unmarshal(content, payload)
+		if unmarshalError != nil {
+			NotifierSendError(request.notifier, unmarshalError)
+			return nil
+		}
+
+		if "" == payload.Id || sessionLifetimeExceeded(server, time.UnixMilli(payload.CreatedAt), time.UnixMilli(payload.UpdatedAt), time.Now()) {
+			return nil
+		}
+
+		data, decodeError := SessionCodecDecode(server.sessionCodec, payload.Content)
+		if decodeError != nil {
+			NotifierSendError(request.notifier, decodeError)
+			return nil
+		}
+
+		payload.Data = data
+
+		return payload
+	}
+
+	return nil
+}
+
+// sessionCookieStale checks if the cookies of the session must be sent again
+// in order to extend the idle lifetime of the session.
+func sessionCookieStale(server *Server, payload *sessionCookiePayload, now time.Time) bool {
+	if server.sessionIdleLifetime <= 0 {
+		return false
+	}
+
+	return now.Sub(time.UnixMilli(payload.UpdatedAt)) >= server.sessionIdleLifetime/2
+}
+
+// sessionCookieWrite encrypts the session and sends it to the client,
+// replacing any session cookie previously sent with the response.
+//
+// It returns an error and sends nothing if the session can't be stored in cookies.
+func sessionCookieWrite(request *Request, response *Response, keys []cipher.AEAD, payload *sessionCookiePayload) error {
+	server := request.server
+	data, encodeError := SessionCodecEncode(server.sessionCodec, payload.Data)
+	if encodeError != nil {
+		return encodeError
+	}
+
+	updated := *payload
+	updated.UpdatedAt = time.Now().UnixMilli()
+	updated.Content = data
+	content, marshalError := json.Marshal(updated)
+	if marshalError != nil {
+		return marshalError
+	}
+
+	aead := keys[0]
+	nonce := make([]byte, aead.NonceSize())
+	_, readError := rand.Read(nonce)
+	if readError != nil {
+		return readError
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, content, []byte(server.sessionCookie.Name)))
+
+	var chunks []string
+	for len(value) > sessionCookieChunkSize {
+		chunks = append(chunks, value[:sessionCookieChunkSize])
+		value = value[sessionCookieChunkSize:]
+	}
+	chunks = append(chunks, value)
+
+	if len(chunks) > sessionCookieChunksMax {
+		return fmt.Errorf("session would take %d cookies, but only %d are allowed", len(chunks), sessionCookieChunksMax)
+	}
+
+	payload.UpdatedAt = updated.UpdatedAt
+	for index := range sessionCookieChunksMax {
+		name := sessionCookieChunkName(server, index)
+		sendCookieWithdraw(response, name)
+
+		cookie := *server.sessionCookie
+		cookie.Name = name
+		if index < len(chunks) {
+			cookie.Value = chunks[index]
+			SendCookie(response, &cookie)
+			continue
+		}
+
+		// Chunks left over by a larger session must be removed.
+		_, cookieError := request.httpRequest.Cookie(name)
+		if nil == cookieError {
+			cookie.MaxAge = -1
+			cookie.Expires = time.Time{}
+			SendCookie(response, &cookie)
+		}
+	}
+
+	return nil
+}
+
+// sessionCookieClear removes all session cookies from the client.
+func sessionCookieClear(request *Request, response *Response) {
+	server := request.server
+	for index := range sessionCookieChunksMax {
+		name := sessionCookieChunkName(server, index)
+		sendCookieWithdraw(response, name)
+
+		_, cookieError := request.httpRequest.Cookie(name)
+		if 0 == index || nil == cookieError {
+			cookie := *server.sessionCookie
+			cookie.Name = name
+			cookie.MaxAge = -1
+			cookie.Expires = time.Time{}
+			SendCookie(response, &cookie)
+		}
+	}
+}
+
+// sessionCookieOperatorCreate creates a session operator bound to the given request,
+// which keeps the session in memory and sends it to the client on every change.
+//
+// Changes that can't be sent to the client are reverted.
+func sessionCookieOperatorCreate(request *Request, response *Response, keys []cipher.AEAD, payload *sessionCookiePayload) SessionListingOperator {
+	var lock sync.Mutex
+
+	// update applies a change to the session and sends it to the client,
+	// restoring the previous value of the property if it can't be sent.
+	update := func(key string, change func()) {
+		lock.Lock()
+		defer lock.Unlock()
+		previous, existed := payload.Data[key]
+		change()
+		writeError := sessionCookieWrite(request, response, keys, payload)
+		if writeError == nil {
+			return
+		}
+
+		if existed {
+			payload.Data[key] = previous
+		} else {
+			delete(payload.Data, key)
+		}
+
+		NotifierSendError(request.notifier, fmt.Errorf("could not store session property `%s` in cookies: %w", key, writeError))
+	}
+
+	return func(
+		sessionId string,
+		withGetter func(get SessionGetter),
+		withSetter func(set SessionSetter),
+		withUnsetter func(unset SessionUnsetter),
+		withValidator func(validate SessionValidator),
+		withDestroyer func(destroy SessionDestroyer),
+		withLister func(list SessionLister),
+	) {
+		withGetter(func(key string, defaultValue any) (value any) {
+			lock.Lock()
+			defer lock.Unlock()
+			sessionItem, ok := payload.Data[key]
+			if !ok {
+				return defaultValue
+			}
+
+			return sessionItem
+		})
+
+		withSetter(func(key string, value any) {
+			update(key, func() {
+				payload.Data[key] = value
+			})
+		})
+
+		withUnsetter(func(key string) {
+			lock.Lock()
+			_, ok := payload.Data[key]
+			lock.Unlock()
+			if !ok {
+				return
+			}
+
+			update(key, func() {
+				delete(payload.Data, key)
+			})
+		})
+
+		withValidator(func() bool {
+			// Expired sessions are discarded when they're read.
+			return true
+		})
+
+		withDestroyer(func() {
+			lock.Lock()
+			defer lock.Unlock()
+			payload.Data = map[string]any{}
+			sessionCookieClear(request, response)
+		})
+
+		withLister(func() (data map[string]any) {
+			lock.Lock()
+			defer lock.Unlock()
+			data = map[string]any{}
+			for key, value := range payload.Data {
+				data[key] = value
+			}
+			return
+		})
+	}
+}
+
+// sessionCookieCreate creates a session stored in cookies from the given payload,
+// sending the cookies to the client only if the session is new or stale.
+func sessionCookieCreate(request *Request, response *Response, keys []cipher.AEAD, payload *sessionCookiePayload) *Session {
+	session := sessionCreateWithOperator(sessionCookieOperatorCreate(request, response, keys, payload), payload.Id)
+	session.createdAt = time.UnixMilli(payload.CreatedAt)
+	if 0 == payload.UpdatedAt || sessionCookieStale(request.server, payload, time.Now()) {
+		writeError := sessionCookieWrite(request, response, keys, payload)
+		if writeError != nil {
+			NotifierSendError(request.notifier, writeError)
+		}
+	}
+	request.session = session
+	request.sessionId = session.id
+	return session
+}
+
+// sessionCookieCreateFresh creates a session stored in cookies with a new id.
+func sessionCookieCreateFresh(request *Request, response *Response, keys []cipher.AEAD) *Session {
+	uuidV4, sessionIdError := uuid.NewV4()
+	if sessionIdError != nil {
+		NotifierSendError(request.notifier, sessionIdError)
+	}
+
+	payload := &sessionCookiePayload{
+		Id:        uuidV4.String(),
+		CreatedAt: time.Now().UnixMilli(),
+		Data:      map[string]any{},
+	}
+
+	MetricsCounterAdd(request.server.metrics, "frizzante_sessions_created_total", nil, 1)
+	return sessionCookieCreate(request, response, keys, payload)
+}
@@ -0,0 +1,106 @@
+package frizzante
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerWithSessionCookieStore(test *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithSessionCookieStore(server, oldKey)
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /")
+		serve(func(request *Request, response *Response) {
+			get, _, _ := SessionStart(request, response)
+			SendEcho(response, get("name", "world").(string))
+		})
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("POST /")
+		serve(func(request *Request, response *Response) {
+			_, set, _ := SessionStart(request, response)
+			set("name", ReceiveMessage(request))
+			SendEcho(response, "")
+		})
+	})
+
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	address := fmt.Sprintf("http://127.0.0.1:%d/", port)
+	addressUrl, _ := url.Parse(address)
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	var cookies []*http.Cookie
+	send := func(method string, body string) string {
+		request, _ := http.NewRequest(method, address, strings.NewReader(body))
+		response, responseError := client.Do(request)
+		if responseError != nil {
+			test.Fatal(responseError)
+		}
+		defer response.Body.Close()
+		cookies = response.Cookies()
+		content, _ := io.ReadAll(response.Body)
+		return string(content)
+	}
+
+	large := strings.Repeat("a", 6000)
+	send("POST", large)
+	if len(jar.Cookies(addressUrl)) < 3 {
+		test.Fatalf("large session was expected to be split across cookies, received %d cookies instead", len(jar.Cookies(addressUrl)))
+	}
+
+	if large != send("GET", "") {
+		test.Fatal("session was expected to be read back from cookies")
+	}
+
+	if 0 != len(cookies) {
+		test.Fatalf("unchanged session was not expected to be sent again, received %d cookies instead", len(cookies))
+	}
+
+	send("POST", strings.Repeat("b", 20000))
+	if large != send("GET", "") {
+		test.Fatal("session too large for cookies was expected to keep its previous value")
+	}
+
+	ServerWithSessionCookieStore(server, newKey, oldKey)
+	if large != send("GET", "") {
+		test.Fatal("session encrypted with an old key was expected to be decrypted")
+	}
+
+	ServerWithSessionCookieStore(server, newKey)
+	send("POST", "test")
+	if "test" != send("GET", "") {
+		test.Fatal("session was expected to be encrypted with the new key")
+	}
+
+	if 1 != len(jar.Cookies(addressUrl)) {
+		test.Fatalf("left over chunks were expected to be removed, received %d cookies instead", len(jar.Cookies(addressUrl)))
+	}
+
+	ServerWithSessionCookieStore(server, oldKey)
+	if "world" != send("GET", "") {
+		test.Fatal("session encrypted with an unknown key was expected to be discarded")
+	}
+}
@@ -0,0 +1,303 @@
+package frizzante
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type sessionRecord struct {
+	CreatedAt int64          `json:"created_at"`
+	UpdatedAt int64          `json:"updated_at"`
+	Content   []byte         `json:"data"`
+	Data      map[string]any `json:"-"`
+}
+
+var sessionIdPattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// SessionOperatorFileCreate creates a session operator that persists each session
+// to a json file in the "sessions" directory of the temporary directory of the server.
+//
+// Session data is encoded with the session codec of the server, see ServerWithSessionCodec.
+//
+// Files are written atomically, so that a crash never leaves a session half written.
+//
+// The store of a session is created when the session is first written to.
+//
+// It also returns a clean function, which removes the files of expired sessions,
+// use ServerWithSessionCleaner to invoke it periodically.
+//
+// Use ServerWithSessionListingOperator to set the operator.
+func SessionOperatorFileCreate(server *Server) (operator SessionListingOperator, clean func()) {
+	var lock sync.Mutex
+
+	directory := func() string {
+		return filepath.Join(server.temporaryDirectory, "sessions")
+	}
+
+	// read reads a session file, it must be invoked while holding the lock.
+	//
+	// It returns a nil record without error if the session has no file.
+	read := func(fileName string) (record *sessionRecord, err error) {
+		content, readError := os.ReadFile(fileName)
+		if readError != nil {
+			if errors.Is(readError, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, readError
+		}
+
+		record = &sessionRecord{}
+		unmarshalError := json.Unmarshal(content, record)
+		if unmarshalError != nil {
+			return nil, unmarshalError
+		}
+
+		data, decodeError := SessionCodecDecode(server.sessionCodec, record.Content)
+		if decodeError != nil {
+			return nil, decodeError
+		}
+
+		record.Data = data
+
+		return record, nil
+	}
+
+	// write writes a session file atomically, it must be invoked while holding the lock.
+	write := func(fileName string, record *sessionRecord) {
+		record.UpdatedAt = time.Now().UnixMilli()
+		data, encodeError := SessionCodecEncode(server.sessionCodec, record.Data)
+		if encodeError != nil {
+			NotifierSendError(server.notifier, encodeError)
+			return
+		}
+
+		record.Content = data
+		content, marshalError := json.Marshal(record)
+		if marshalError != nil {
+			NotifierSendError(server.notifier, marshalError)
+			return
+		}
+
+		mkdirError := os.MkdirAll(directory(), os.ModePerm)
+		if mkdirError != nil {
+			NotifierSendError(server.notifier, mkdirError)
+			return
+		}
+
+		file, createError := os.CreateTemp(directory(), ".session-*")
+		if createError != nil {
+			NotifierSendError(server.notifier, createError)
+			return
+		}
+
+		_, writeError := file.Write(content)
+		closeError := file.Close()
+		if nil == writeError {
+			writeError = closeError
+		}
+
+		if writeError != nil {
+			NotifierSendError(server.notifier, writeError)
+			_ = os.Remove(file.Name())
+			return
+		}
+
+		renameError := os.Rename(file.Name(), fileName)
+		if renameError != nil {
+			NotifierSendError(server.notifier, renameError)
+			_ = os.Remove(file.Name())
+		}
+	}
+
+	// create creates an empty record.
+	create := func() *sessionRecord {
+		return &sessionRecord{
+			CreatedAt: time.Now().UnixMilli(),
+			Data:      map[string]any{},
+		}
+	}
+
+	operator = func(
+		sessionId string,
+		withGetter func(get SessionGetter),
+		withSetter func(set SessionSetter),
+		withUnsetter func(unset SessionUnsetter),
+		withValidator func(validate SessionValidator),
+		withDestroyer func(destroy SessionDestroyer),
+		withLister func(list SessionLister),
+	) {
+		// Session ids come from cookies, they must never escape the directory.
+		valid := sessionIdPattern.MatchString(sessionId)
+		fileName := filepath.Join(directory(), sessionId+".json")
+
+		withGetter(func(key string, defaultValue any) (value any) {
+			if !valid {
+				return defaultValue
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			record, readError := read(fileName)
+			if readError != nil {
+				NotifierSendError(server.notifier, readError)
+				return defaultValue
+			}
+
+			if nil == record {
+				return defaultValue
+			}
+
+			sessionItem, ok := record.Data[key]
+			if !ok {
+				return defaultValue
+			}
+
+			return sessionItem
+		})
+
+		withSetter(func(key string, value any) {
+			if !valid {
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			record, readError := read(fileName)
+			if readError != nil {
+				// Writing anyway would overwrite a session that may still exist.
+				NotifierSendError(server.notifier, readError)
+				return
+			}
+
+			if nil == record {
+				record = create()
+			}
+
+			record.Data[key] = value
+			write(fileName, record)
+		})
+
+		withUnsetter(func(key string) {
+			if !valid {
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			record, readError := read(fileName)
+			if readError != nil {
+				NotifierSendError(server.notifier, readError)
+				return
+			}
+
+			if nil == record {
+				return
+			}
+
+			delete(record.Data, key)
+			write(fileName, record)
+		})
+
+		withValidator(func() bool {
+			if !valid {
+				return false
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			record, readError := read(fileName)
+			if readError != nil {
+				// Sessions are not destroyed because of errors that may be transient.
+				NotifierSendError(server.notifier, readError)
+				return true
+			}
+
+			if nil == record {
+				return true
+			}
+
+			return !sessionPersistedExpired(server, sessionId, time.UnixMilli(record.CreatedAt), time.UnixMilli(record.UpdatedAt))
+		})
+
+		withDestroyer(func() {
+			if !valid {
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			removeError := os.Remove(fileName)
+			if removeError != nil && !errors.Is(removeError, os.ErrNotExist) {
+				NotifierSendError(server.notifier, removeError)
+			}
+		})
+
+		withLister(func() (data map[string]any) {
+			data = map[string]any{}
+			if !valid {
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			record, readError := read(fileName)
+			if readError != nil {
+				NotifierSendError(server.notifier, readError)
+				return
+			}
+
+			if nil == record {
+				return
+			}
+
+			for key, value := range record.Data {
+				data[key] = value
+			}
+			return
+		})
+	}
+
+	clean = func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		entries, readError := os.ReadDir(directory())
+		if readError != nil {
+			if !errors.Is(readError, os.ErrNotExist) {
+				NotifierSendError(server.notifier, readError)
+			}
+			return
+		}
+
+		for _, entry := range entries {
+			sessionId, isSession := strings.CutSuffix(entry.Name(), ".json")
+			if entry.IsDir() || !isSession || strings.HasPrefix(sessionId, ".") {
+				continue
+			}
+
+			fileName := filepath.Join(directory(), entry.Name())
+			record, readError := read(fileName)
+			if readError != nil {
+				NotifierSendError(server.notifier, readError)
+				continue
+			}
+
+			if nil != record && !sessionPersistedExpired(server, sessionId, time.UnixMilli(record.CreatedAt), time.UnixMilli(record.UpdatedAt)) {
+				continue
+			}
+
+			removeError := os.Remove(fileName)
+			if removeError != nil && !errors.Is(removeError, os.ErrNotExist) {
+				NotifierSendError(server.notifier, removeError)
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,123 @@
+package frizzante
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionOperatorFileCreate(test *testing.T) {
+	temporaryDirectory := test.TempDir()
+
+	serverCreate := func() (server *Server, port int) {
+		server = ServerCreate()
+		port = NextNumber(8080)
+		ServerWithPort(server, port)
+		ServerWithTemporaryDirectory(server, temporaryDirectory)
+		operator, _ := SessionOperatorFileCreate(server)
+		ServerWithSessionListingOperator(server, operator)
+		ServerWithApi(server, func(
+			route func(pattern string),
+			serve func(serveFunction func(req *Request, res *Response)),
+		) {
+			route("GET /")
+			serve(func(request *Request, response *Response) {
+				get, _, _ := SessionStart(request, response)
+				SendEcho(response, get("name", "world").(string))
+			})
+		})
+		ServerWithApi(server, func(
+			route func(pattern string),
+			serve func(serveFunction func(req *Request, res *Response)),
+		) {
+			route("POST /")
+			serve(func(request *Request, response *Response) {
+				_, set, _ := SessionStart(request, response)
+				set("name", ReceiveMessage(request))
+				SendEcho(response, request.sessionId)
+			})
+		})
+		return
+	}
+
+	send := func(method string, port int, sessionId string, body string) (string, string) {
+		request, _ := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d/", port), strings.NewReader(body))
+		if "" != sessionId {
+			request.AddCookie(&http.Cookie{Name: "session-id", Value: sessionId})
+		}
+		response, responseError := http.DefaultClient.Do(request)
+		if responseError != nil {
+			test.Fatal(responseError)
+		}
+		content, _ := io.ReadAll(response.Body)
+		cookieId := ""
+		for _, cookie := range response.Cookies() {
+			if "session-id" == cookie.Name {
+				cookieId = cookie.Value
+			}
+		}
+		return string(content), cookieId
+	}
+
+	server1, port1 := serverCreate()
+	go ServerStart(server1)
+	time.Sleep(1 * time.Second)
+
+	sessionId, _ := send("POST", port1, "", "test")
+	ServerStop(server1)
+
+	if !Exists(filepath.Join(temporaryDirectory, "sessions", sessionId+".json")) {
+		test.Fatal("session was expected to be persisted to a file")
+	}
+
+	server2, port2 := serverCreate()
+	go ServerStart(server2)
+	defer ServerStop(server2)
+	time.Sleep(1 * time.Second)
+
+	content, cookieId := send("GET", port2, sessionId, "")
+	if "test" != content || sessionId != cookieId {
+		test.Fatalf("session was expected to survive a restart, received '%s' from session '%s' instead", content, cookieId)
+	}
+
+	content, cookieId = send("GET", port2, "bb7c2bc5-a2e8-4a4c-9c5e-b3f0a4ed6f4a", "")
+	if "world" != content || "bb7c2bc5-a2e8-4a4c-9c5e-b3f0a4ed6f4a" == cookieId {
+		test.Fatalf("unknown session was expected to be replaced, received '%s' from session '%s' instead", content, cookieId)
+	}
+
+	cleaner := ServerCreate()
+	ServerWithTemporaryDirectory(cleaner, temporaryDirectory)
+	ServerWithSessionIdleLifetime(cleaner, time.Millisecond)
+	_, clean := SessionOperatorFileCreate(cleaner)
+	time.Sleep(10 * time.Millisecond)
+	clean()
+
+	entries, _ := os.ReadDir(filepath.Join(temporaryDirectory, "sessions"))
+	if 0 != len(entries) {
+		test.Fatalf("expired sessions were expected to be removed, received %d files instead", len(entries))
+	}
+
+	corruptId := "bb7c2bc5-a2e8-4a4c-9c5e-b3f0a4ed6f4a"
+	corruptFileName := filepath.Join(temporaryDirectory, "sessions", corruptId+".json")
+	_ = os.WriteFile(corruptFileName, []byte("{"), os.ModePerm)
+	operator, _ := SessionOperatorFileCreate(cleaner)
+	operator(
+		corruptId,
+		func(get SessionGetter) {},
+		func(set SessionSetter) { set("name", "test") },
+		func(unset SessionUnsetter) {},
+		func(validate SessionValidator) {},
+		func(destroy SessionDestroyer) {},
+		func(list SessionLister) {},
+	)
+
+	corruptContent, _ := os.ReadFile(corruptFileName)
+	if "{" != string(corruptContent) {
+		test.Fatalf("unreadable sessions were expected to be left untouched, received '%s' instead", corruptContent)
+	}
+}
@@ -0,0 +1,249 @@
+package frizzante
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type sessionSqlQueries struct {
+	migrate string
+	find    string
+	save    string
+	remove  string
+	expired string
+}
+
+// sessionSqlQueriesCreate creates the queries used by the sql session operator for the given dialect.
+func sessionSqlQueriesCreate(dialect SqlDialect, table string) sessionSqlQueries {
+	queries := sessionSqlQueries{
+		migrate: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id VARCHAR(64) NOT NULL PRIMARY KEY, data LONGBLOB NOT NULL, created_at BIGINT NOT NULL, updated_at BIGINT NOT NULL)", table),
+		find:    fmt.Sprintf("SELECT data, created_at, updated_at FROM %s WHERE id = ?", table),
+		save:    fmt.Sprintf("INSERT INTO %s (id, data, created_at, updated_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE data = VALUES(data), updated_at = VALUES(updated_at)", table),
+		remove:  fmt.Sprintf("DELETE FROM %s WHERE id = ?", table),
+		expired: fmt.Sprintf("SELECT id FROM %s WHERE updated_at < ? OR created_at < ?", table),
+	}
+
+	switch dialect {
+	case SqlDialectPostgresql:
+		queries.migrate = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id VARCHAR(64) NOT NULL PRIMARY KEY, data BYTEA NOT NULL, created_at BIGINT NOT NULL, updated_at BIGINT NOT NULL)", table)
+		queries.save = fmt.Sprintf("INSERT INTO %s (id, data, created_at, updated_at) VALUES (?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at", table)
+	case SqlDialectSqlite:
+		queries.migrate = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id TEXT NOT NULL PRIMARY KEY, data BLOB NOT NULL, created_at INTEGER NOT NULL, updated_at INTEGER NOT NULL)", table)
+		queries.save = fmt.Sprintf("INSERT INTO %s (id, data, created_at, updated_at) VALUES (?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at", table)
+	}
+
+	return queries
+}
+
+// SessionOperatorSqlCreate creates a session operator that persists sessions to a table,
+// using any dialect, SqlDialectMysql, SqlDialectPostgresql or SqlDialectSqlite.
+//
+// The table is created if it doesn't exist yet, each session is a row
+// with its data encoded with the session codec of the server, see ServerWithSessionCodec.
+//
+// The store of a session is created when the session is first written to.
+//
+// It also returns a clean function, which removes the rows of expired sessions,
+// use ServerWithSessionCleaner to invoke it periodically.
+//
+// Use ServerWithSessionListingOperator to set the operator.
+func SessionOperatorSqlCreate(server *Server, sql *Sql, table string) (operator SessionListingOperator, clean func()) {
+	var lock sync.Mutex
+
+	if !sqlIdentifierPattern.MatchString(table) {
+		NotifierSendError(server.notifier, fmt.Errorf("session table `%s` is not a valid table name", table))
+		table = "sessions"
+	}
+
+	queries := sessionSqlQueriesCreate(sql.dialect, table)
+	SqlExecute(sql, queries.migrate)
+
+	// read reads a session row, it must be invoked while holding the lock.
+	//
+	// It returns a nil record without error if the session has no row.
+	read := func(sessionId string) (record *sessionRecord, err error) {
+		rows, findError := SqlTryFind(sql, queries.find, sessionId)
+		if findError != nil {
+			return nil, findError
+		}
+		defer SqlRowsClose(rows)
+
+		var data []byte
+		record = &sessionRecord{}
+		if !SqlRowsNext(rows, &data, &record.CreatedAt, &record.UpdatedAt) {
+			return nil, SqlRowsError(rows)
+		}
+
+		decoded, decodeError := SessionCodecDecode(server.sessionCodec, data)
+		if decodeError != nil {
+			return nil, decodeError
+		}
+
+		record.Data = decoded
+
+		return record, nil
+	}
+
+	// write writes a session row, it must be invoked while holding the lock.
+	write := func(sessionId string, record *sessionRecord) {
+		record.UpdatedAt = time.Now().UnixMilli()
+		data, encodeError := SessionCodecEncode(server.sessionCodec, record.Data)
+		if encodeError != nil {
+			NotifierSendError(server.notifier, encodeError)
+			return
+		}
+
+		SqlExecute(sql, queries.save, sessionId, data, record.CreatedAt, record.UpdatedAt)
+	}
+
+	// create creates an empty record.
+	create := func() *sessionRecord {
+		return &sessionRecord{
+			CreatedAt: time.Now().UnixMilli(),
+			Data:      map[string]any{},
+		}
+	}
+
+	operator = func(
+		sessionId string,
+		withGetter func(get SessionGetter),
+		withSetter func(set SessionSetter),
+		withUnsetter func(unset SessionUnsetter),
+		withValidator func(validate SessionValidator),
+		withDestroyer func(destroy SessionDestroyer),
+		withLister func(list SessionLister),
+	) {
+		withGetter(func(key string, defaultValue any) (value any) {
+			lock.Lock()
+			defer lock.Unlock()
+			record, readError := read(sessionId)
+			if readError != nil {
+				NotifierSendError(server.notifier, readError)
+				return defaultValue
+			}
+
+			if nil == record {
+				return defaultValue
+			}
+
+			sessionItem, ok := record.Data[key]
+			if !ok {
+				return defaultValue
+			}
+
+			return sessionItem
+		})
+
+		withSetter(func(key string, value any) {
+			lock.Lock()
+			defer lock.Unlock()
+			record, readError := read(sessionId)
+			if readError != nil {
+				// Writing anyway would overwrite a session that may still exist.
+				NotifierSendError(server.notifier, readError)
+				return
+			}
+
+			if nil == record {
+				record = create()
+			}
+
+			record.Data[key] = value
+			write(sessionId, record)
+		})
+
+		withUnsetter(func(key string) {
+			lock.Lock()
+			defer lock.Unlock()
+			record, readError := read(sessionId)
+			if readError != nil {
+				NotifierSendError(server.notifier, readError)
+				return
+			}
+
+			if nil == record {
+				return
+			}
+
+			delete(record.Data, key)
+			write(sessionId, record)
+		})
+
+		withValidator(func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			record, readError := read(sessionId)
+			if readError != nil {
+				// Sessions are not destroyed because of errors that may be transient.
+				NotifierSendError(server.notifier, readError)
+				return true
+			}
+
+			if nil == record {
+				return true
+			}
+
+			return !sessionPersistedExpired(server, sessionId, time.UnixMilli(record.CreatedAt), time.UnixMilli(record.UpdatedAt))
+		})
+
+		withDestroyer(func() {
+			lock.Lock()
+			defer lock.Unlock()
+			SqlExecute(sql, queries.remove, sessionId)
+		})
+
+		withLister(func() (data map[string]any) {
+			lock.Lock()
+			defer lock.Unlock()
+			data = map[string]any{}
+			record, readError := read(sessionId)
+			if readError != nil {
+				NotifierSendError(server.notifier, readError)
+				return
+			}
+
+			if nil == record {
+				return
+			}
+
+			for key, value := range record.Data {
+				data[key] = value
+			}
+			return
+		})
+	}
+
+	clean = func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		now := time.Now()
+		var updatedBefore int64
+		if server.sessionIdleLifetime > 0 {
+			updatedBefore = now.Add(-server.sessionIdleLifetime).UnixMilli()
+		}
+
+		var createdBefore int64
+		if server.sessionMaxLifetime > 0 {
+			createdBefore = now.Add(-server.sessionMaxLifetime).UnixMilli()
+		}
+
+		var sessionIds []string
+		var sessionId string
+		next, close := SqlFind(sql, queries.expired, updatedBefore, createdBefore)
+		for next(&sessionId) {
+			sessionIds = append(sessionIds, sessionId)
+		}
+		close()
+
+		for _, sessionId := range sessionIds {
+			if _, managed := sessionManagerFind(server.sessionManager, sessionId); managed {
+				continue
+			}
+			SqlExecute(sql, queries.remove, sessionId)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,82 @@
+package frizzante
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionOperatorSqlCreate(test *testing.T) {
+	server := ServerCreate()
+	sqlite := sqliteCreate(test)
+	SqlWithNotifier(sqlite, server.notifier)
+
+	operator, clean := SessionOperatorSqlCreate(server, sqlite, "sessions")
+
+	var get SessionGetter
+	var set SessionSetter
+	var unset SessionUnsetter
+	var validate SessionValidator
+	var destroy SessionDestroyer
+	var list SessionLister
+	operate := func(sessionId string) {
+		operator(
+			sessionId,
+			func(value SessionGetter) { get = value },
+			func(value SessionSetter) { set = value },
+			func(value SessionUnsetter) { unset = value },
+			func(value SessionValidator) { validate = value },
+			func(value SessionDestroyer) { destroy = value },
+			func(value SessionLister) { list = value },
+		)
+	}
+
+	operate("session-1")
+	if 0 != len(list()) {
+		test.Fatal("session was expected to be empty before being written to")
+	}
+
+	set("name", "test")
+	set("fruit", "apples")
+	unset("fruit")
+	if "test" != get("name", "world") {
+		test.Fatalf("session was expected to contain name, received %v instead", get("name", "world"))
+	}
+
+	if !validate() {
+		test.Fatal("session was expected to be valid after being written to")
+	}
+
+	set("count", 3)
+	if 3 != get("count", 0) {
+		test.Fatalf("count was expected to be decoded as an int, received %#v instead", get("count", 0))
+	}
+	unset("count")
+
+	data := list()
+	if 1 != len(data) || "test" != data["name"] {
+		test.Fatalf("session was expected to list its data, received %v instead", data)
+	}
+
+	destroy()
+	if 0 != len(list()) {
+		test.Fatal("session was expected to be empty after being destroyed")
+	}
+
+	operate("session-2")
+	set("name", "test")
+	ServerWithSessionIdleLifetime(server, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if validate() {
+		test.Fatal("expired session was expected to be invalid")
+	}
+
+	clean()
+
+	var count int
+	next, close := SqlFind(sqlite, "SELECT COUNT(*) FROM sessions")
+	next(&count)
+	close()
+	if 0 != count {
+		test.Fatalf("expired sessions were expected to be removed, received %d rows instead", count)
+	}
+}
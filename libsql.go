@@ -1,6 +1,14 @@
 package frizzante
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"time"
+)
+
+// sqlIdentifierPattern matches identifiers, like table names, that are safe to write into queries unquoted.
+var sqlIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 
 func sqlFindNextFallback(dest ...any) bool { return false }
 func sqlFindCloseFallback()                {}
@@ -10,18 +18,32 @@ type SqlDialect int64
 const (
 	SqlDialectMysql      SqlDialect = 0
 	SqlDialectPostgresql SqlDialect = 1
+	SqlDialectSqlite     SqlDialect = 2
 )
 
 type Sql struct {
-	database *sql.DB
-	dialect  SqlDialect
-	notifier *Notifier
+	database   *sql.DB
+	dialect    SqlDialect
+	notifier   *Notifier
+	statements *sqlStatementCache
+	pool       []func(database *sql.DB)
+}
+
+type SqlStatistics struct {
+	sql.DBStats
+	StatementsCached     int
+	StatementCacheHits   uint64
+	StatementCacheMisses uint64
 }
 
 // SqlCreate creates a sql wrapper.
+//
+// Prepared statements are cached, see SqlWithStatementCacheSize.
 func SqlCreate() *Sql {
 	return &Sql{
-		dialect: SqlDialectMysql,
+		dialect:    SqlDialectMysql,
+		statements: sqlStatementCacheCreate(100),
+		pool:       []func(database *sql.DB){},
 	}
 }
 
@@ -31,47 +53,161 @@ func SqlWithNotifier(self *Sql, notifier *Notifier) {
 }
 
 // SqlWithDatabase sets the sql database.
+//
+// Pool settings set with SqlWithMaxOpenConnections and similar functions are applied to database.
 func SqlWithDatabase(self *Sql, database *sql.DB) {
+	if nil != self.database {
+		sqlStatementCacheClear(self)
+	}
+
 	self.database = database
+	for _, configure := range self.pool {
+		configure(database)
+	}
+}
+
+// sqlWithPool adds a pool setting, applying it right away if the database is already set.
+func sqlWithPool(self *Sql, configure func(database *sql.DB)) {
+	self.pool = append(self.pool, configure)
+	if nil != self.database {
+		configure(self.database)
+	}
+}
+
+// SqlWithMaxOpenConnections sets the maximum number of open connections, unlimited by default.
+func SqlWithMaxOpenConnections(self *Sql, maxOpenConnections int) {
+	sqlWithPool(self, func(database *sql.DB) { database.SetMaxOpenConns(maxOpenConnections) })
+}
+
+// SqlWithMaxIdleConnections sets the maximum number of idle connections, 2 by default.
+func SqlWithMaxIdleConnections(self *Sql, maxIdleConnections int) {
+	sqlWithPool(self, func(database *sql.DB) { database.SetMaxIdleConns(maxIdleConnections) })
+}
+
+// SqlWithConnectionMaxLifetime sets for how long connections are reused, forever by default.
+func SqlWithConnectionMaxLifetime(self *Sql, lifetime time.Duration) {
+	sqlWithPool(self, func(database *sql.DB) { database.SetConnMaxLifetime(lifetime) })
+}
+
+// SqlWithConnectionMaxIdleTime sets for how long connections can stay idle before being closed, forever by default.
+func SqlWithConnectionMaxIdleTime(self *Sql, idleTime time.Duration) {
+	sqlWithPool(self, func(database *sql.DB) { database.SetConnMaxIdleTime(idleTime) })
 }
 
-// SqlWithDialect sets the sql dialect.
+// SqlWithStatementCacheSize sets how many prepared statements are cached, 100 by default.
+//
+// Statements are cached by query, the least recently used statements are closed past size,
+// statements failing because of a broken connection are prepared again.
+//
+// Queries of transactions, like those of SqlExecute, use statements already cached by other queries,
+// but never prepare new ones.
+//
+// Use 0 to prepare statements every time instead.
+func SqlWithStatementCacheSize(self *Sql, size int) {
+	self.statements.lock.Lock()
+	defer self.statements.lock.Unlock()
+	self.statements.size = max(size, 0)
+	sqlStatementCacheEvict(self)
+}
+
+// SqlStats reads the statistics of the connection pool and of the statement cache.
+func SqlStats(self *Sql) SqlStatistics {
+	statistics := SqlStatistics{}
+	if nil != self.database {
+		statistics.DBStats = self.database.Stats()
+	}
+
+	self.statements.lock.Lock()
+	defer self.statements.lock.Unlock()
+	statistics.StatementsCached = self.statements.order.Len()
+	statistics.StatementCacheHits = self.statements.hits
+	statistics.StatementCacheMisses = self.statements.misses
+	return statistics
+}
+
+// ServerWithSql sets the sql wrapper of the server,
+// whose statistics are added to the metrics of the server.
+func ServerWithSql(self *Server, sql *Sql) {
+	self.sql = sql
+}
+
+// SqlWithDialect sets the sql dialect, SqlDialectMysql by default.
+//
+// Queries are written with "?" placeholders regardless of the dialect,
+// they're rewritten as required by the dialect, like "$1" for SqlDialectPostgresql,
+// use "??" for a literal "?".
+//
+// When the parameters of a query consist of a single map or struct,
+// placeholders can also be written as ":name", like "SELECT * FROM users WHERE email = :email".
 func SqlWithDialect(self *Sql, dialect SqlDialect) {
 	self.dialect = dialect
 }
 
+// sqlSpanStart starts a span tracing a sql statement.
+func sqlSpanStart(self *Sql, ctx context.Context, name string, query string) (context.Context, *Span) {
+	ctx, span := SpanStart(ctx, name)
+	system := "mysql"
+	switch self.dialect {
+	case SqlDialectPostgresql:
+		system = "postgresql"
+	case SqlDialectSqlite:
+		system = "sqlite"
+	}
+	SpanWithAttribute(span, "db.system", system)
+	SpanWithAttribute(span, "db.statement", query)
+	return ctx, span
+}
+
+type SqlRows struct {
+	sql     *Sql
+	release func() error
+	rows    *sql.Rows
+	span    *Span
+	err     error
+	closed  bool
+}
+
 // SqlExecute executes sql queries that don't return rows, typically INSERT, UPDATE, DELETE queries.
+//
+// Errors are sent to the notifier, in which case it returns nil, see SqlTryExecute.
 func SqlExecute(self *Sql, query string, props ...any) *sql.Result {
-	transaction, transactionError := self.database.Begin()
-	if transactionError != nil {
-		NotifierSendError(self.notifier, transactionError)
-		NotifierSendError(self.notifier, transactionError)
-		return nil
-	}
+	return SqlExecuteWithContext(self, context.Background(), query, props...)
+}
 
-	statement, statementError := transaction.Prepare(query)
-	if nil != statementError {
-		NotifierSendError(self.notifier, statementError)
+// SqlExecuteWithContext executes sql queries that don't return rows, typically INSERT, UPDATE, DELETE queries.
+//
+// The transaction is rolled back as soon as ctx is done.
+func SqlExecuteWithContext(self *Sql, ctx context.Context, query string, props ...any) *sql.Result {
+	result, err := SqlTryExecuteWithContext(self, ctx, query, props...)
+	if err != nil {
+		NotifierSendError(notifierWithContext(self.notifier, ctx), err)
 		return nil
 	}
 
-	result, execError := statement.Exec(props...)
-	if execError != nil {
-		NotifierSendError(self.notifier, execError)
-		rollbackError := transaction.Rollback()
-		if rollbackError != nil {
-			NotifierSendError(self.notifier, rollbackError)
-		}
-		return nil
-	}
+	return &result
+}
 
-	commitError := transaction.Commit()
-	if commitError != nil {
-		NotifierSendError(self.notifier, commitError)
-		return nil
+// SqlTryExecute executes sql queries that don't return rows, typically INSERT, UPDATE, DELETE queries,
+// within a transaction.
+//
+// It returns an error if the query fails, in which case the transaction is rolled back.
+func SqlTryExecute(self *Sql, query string, props ...any) (sql.Result, error) {
+	return SqlTryExecuteWithContext(self, context.Background(), query, props...)
+}
+
+// SqlTryExecuteWithContext works like SqlTryExecute,
+// except the transaction is rolled back as soon as ctx is done.
+func SqlTryExecuteWithContext(self *Sql, ctx context.Context, query string, props ...any) (result sql.Result, err error) {
+	err = SqlTransactionWithContext(self, ctx, func(tx *SqlTx) error {
+		var execError error
+		result, execError = SqlTxExecute(tx, query, props...)
+		return execError
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return &result
+	return result, nil
 }
 
 // SqlFind executes a sql query that returns rows, typically a SELECT query.
@@ -85,40 +221,153 @@ func SqlExecute(self *Sql, query string, props ...any) *sql.Result {
 // Use close to close the database context and prevent any subsequent enumerations.
 //
 // Whenever next returns false, the database context is closed automatically as if calling close.
+//
+// Errors are sent to the notifier, see SqlTryFind to tell empty results from failures.
 func SqlFind(self *Sql, query string, props ...any) (next func(dest ...any) bool, close func()) {
+	return SqlFindWithContext(self, context.Background(), query, props...)
+}
+
+// SqlFindWithContext executes a sql query that returns rows, typically a SELECT query.
+//
+// It works like SqlFind, except the query stops as soon as ctx is done,
+// in which case next returns false.
+func SqlFindWithContext(self *Sql, ctx context.Context, query string, props ...any) (next func(dest ...any) bool, close func()) {
 	next = sqlFindNextFallback
 	close = sqlFindCloseFallback
 
-	statement, statementError := self.database.Prepare(query)
-	if nil != statementError {
-		NotifierSendError(self.notifier, statementError)
-		return
-	}
-	defer statement.Close()
-
-	rows, queryError := statement.Query(props...)
-	if queryError != nil {
-		NotifierSendError(self.notifier, queryError)
+	notifier := notifierWithContext(self.notifier, ctx)
+	rows, findError := SqlTryFindWithContext(self, ctx, query, props...)
+	if findError != nil {
+		NotifierSendError(notifier, findError)
 		return
 	}
 
 	next = func(dest ...any) bool {
-		if !rows.Next() {
-			return false
+		if SqlRowsNext(rows, dest...) {
+			return true
 		}
 
-		scanError := rows.Scan(dest...)
-		if scanError != nil {
-			NotifierSendError(self.notifier, scanError)
-			return false
+		rowsError := SqlRowsError(rows)
+		if rowsError != nil {
+			NotifierSendError(notifier, rowsError)
 		}
-		return true
+		return false
 	}
 	close = func() {
-		err := rows.Close()
-		if err != nil {
-			NotifierSendError(self.notifier, err)
+		closeError := SqlRowsClose(rows)
+		if closeError != nil {
+			NotifierSendError(notifier, closeError)
 		}
 	}
 	return
 }
+
+// SqlTryFind executes a sql query that returns rows, typically a SELECT query.
+//
+// It returns an error if the query fails, otherwise use SqlRowsNext to iterate the rows,
+// then SqlRowsError to find out if the iteration stopped because of an error.
+//
+// Rows are closed automatically when the iteration ends,
+// use SqlRowsClose to stop iterating earlier.
+func SqlTryFind(self *Sql, query string, props ...any) (*SqlRows, error) {
+	return SqlTryFindWithContext(self, context.Background(), query, props...)
+}
+
+// SqlTryFindWithContext works like SqlTryFind, except the query stops as soon as ctx is done,
+// in which case SqlRowsError returns the error of ctx.
+func SqlTryFindWithContext(self *Sql, ctx context.Context, query string, props ...any) (*SqlRows, error) {
+	ctx, span := sqlSpanStart(self, ctx, "sql find", query)
+
+	query, props, rewriteError := sqlQueryRewrite(self.dialect, query, props)
+	if rewriteError != nil {
+		SpanWithError(span, rewriteError)
+		SpanEnd(span)
+		return nil, rewriteError
+	}
+
+	statement, release, statementError := sqlPrepare(self, ctx, query)
+	if nil != statementError {
+		SpanWithError(span, statementError)
+		SpanEnd(span)
+		return nil, statementError
+	}
+
+	rows, queryError := statement.QueryContext(ctx, props...)
+	if queryError != nil {
+		SpanWithError(span, queryError)
+		SpanEnd(span)
+		sqlStatementInvalidate(self, query, statement, queryError)
+		closeError := release()
+		if closeError != nil {
+			NotifierSendError(notifierWithContext(self.notifier, ctx), closeError)
+		}
+		return nil, queryError
+	}
+
+	// Statements live at least as long as their rows, they're released along with them.
+	return &SqlRows{
+		sql:     self,
+		release: release,
+		rows:    rows,
+		span:    span,
+	}, nil
+}
+
+// SqlRowsNext projects the next row onto dest.
+//
+// It returns false when there are no more rows, or when the row can't be projected onto dest,
+// in which case SqlRowsError returns the error.
+func SqlRowsNext(self *SqlRows, dest ...any) bool {
+	if self.closed {
+		return false
+	}
+
+	if !self.rows.Next() {
+		self.err = self.rows.Err()
+		closeError := SqlRowsClose(self)
+		if nil == self.err {
+			self.err = closeError
+		}
+		return false
+	}
+
+	scanError := self.rows.Scan(dest...)
+	if scanError != nil {
+		self.err = scanError
+		_ = SqlRowsClose(self)
+		return false
+	}
+
+	return true
+}
+
+// SqlRowsError returns the error that stopped the iteration of the rows, if any.
+func SqlRowsError(self *SqlRows) error {
+	return self.err
+}
+
+// SqlRowsClose closes the rows along with their statement.
+//
+// Closing rows more than once has no effect.
+func SqlRowsClose(self *SqlRows) error {
+	if self.closed {
+		return nil
+	}
+	self.closed = true
+
+	if nil != self.err {
+		SpanWithError(self.span, self.err)
+	}
+	defer SpanEnd(self.span)
+
+	rowsError := self.rows.Close()
+	var statementError error
+	if nil != self.release {
+		statementError = self.release()
+	}
+
+	if rowsError != nil {
+		return rowsError
+	}
+	return statementError
+}
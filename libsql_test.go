@@ -1,12 +1,97 @@
 package frizzante
 
 import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
 	"testing"
 )
 
+// sqliteCreate creates a sql client backed by an in memory sqlite database,
+// which is closed as soon as the test ends.
+//
+// Each connection opens its own in memory database, so the client is limited to one connection.
+func sqliteCreate(test *testing.T) *Sql {
+	database, openError := sql.Open("sqlite3", ":memory:")
+	if openError != nil {
+		test.Fatal(openError)
+	}
+	test.Cleanup(func() {
+		_ = database.Close()
+	})
+
+	sqlite := SqlCreate()
+	SqlWithNotifier(sqlite, NotifierCreate())
+	SqlWithDialect(sqlite, SqlDialectSqlite)
+	SqlWithMaxOpenConnections(sqlite, 1)
+	SqlWithDatabase(sqlite, database)
+	return sqlite
+}
+
 func TestSqlOperatorCreate(test *testing.T) {
 	sql := SqlCreate()
 	if nil == sql {
 		test.Fatal("could not create sql")
 	}
 }
+
+func TestSqlTryFind(test *testing.T) {
+	sqlite := sqliteCreate(test)
+
+	_, createError := SqlTryExecute(sqlite, "CREATE TABLE fruits (name TEXT NOT NULL)")
+	if createError != nil {
+		test.Fatal(createError)
+	}
+
+	_, insertError := SqlTryExecute(sqlite, "INSERT INTO fruits (name) VALUES (?), (?)", "apples", "pears")
+	if insertError != nil {
+		test.Fatal(insertError)
+	}
+
+	_, failedError := SqlTryExecute(sqlite, "INSERT INTO vegetables (name) VALUES (?)", "carrots")
+	if nil == failedError {
+		test.Fatal("insert into a missing table was expected to fail")
+	}
+
+	rows, findError := SqlTryFind(sqlite, "SELECT name FROM fruits ORDER BY name")
+	if findError != nil {
+		test.Fatal(findError)
+	}
+
+	var names []string
+	var name string
+	for SqlRowsNext(rows, &name) {
+		names = append(names, name)
+	}
+
+	if nil != SqlRowsError(rows) || 2 != len(names) || "pears" != names[1] {
+		test.Fatalf("rows were expected to be apples and pears, received %v and error %v instead", names, SqlRowsError(rows))
+	}
+
+	empty, emptyError := SqlTryFind(sqlite, "SELECT name FROM fruits WHERE name = ?", "plums")
+	if emptyError != nil {
+		test.Fatal(emptyError)
+	}
+
+	if SqlRowsNext(empty, &name) || nil != SqlRowsError(empty) {
+		test.Fatal("empty results were expected to have no rows and no error")
+	}
+
+	_, missingError := SqlTryFind(sqlite, "SELECT name FROM vegetables")
+	if nil == missingError {
+		test.Fatal("find on a missing table was expected to fail")
+	}
+
+	mismatch, _ := SqlTryFind(sqlite, "SELECT name FROM fruits")
+	var first, second string
+	if SqlRowsNext(mismatch, &first, &second) || nil == SqlRowsError(mismatch) {
+		test.Fatal("scanning into the wrong number of destinations was expected to fail")
+	}
+
+	closing, _ := SqlTryFind(sqlite, "SELECT name FROM fruits")
+	if closeError := SqlRowsClose(closing); closeError != nil {
+		test.Fatal(closeError)
+	}
+	if SqlRowsNext(closing, &name) {
+		test.Fatal("closed rows were not expected to be iterated")
+	}
+}
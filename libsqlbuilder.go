@@ -0,0 +1,386 @@
+package frizzante
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type sqlBuilderClause struct {
+	text  string
+	props []any
+}
+
+type sqlBuilderCondition struct {
+	connector string
+	clause    sqlBuilderClause
+}
+
+type sqlBuilderAssignment struct {
+	column string
+	value  any
+}
+
+type SqlBuilder struct {
+	sql       *Sql
+	statement string
+	table     string
+	columns   []string
+	selects   []sqlBuilderClause
+	rows      [][]any
+	sets      []sqlBuilderAssignment
+	joins     []sqlBuilderClause
+	where     []sqlBuilderCondition
+	orderBy   []string
+	limit     int
+	offset    int
+	returning []string
+}
+
+// SqlQuoteIdentifier quotes an identifier according to the dialect,
+// with backticks for SqlDialectMysql and double quotes otherwise.
+//
+// Qualified identifiers, like "users.name", are quoted part by part, "*" is left untouched.
+func SqlQuoteIdentifier(self *Sql, identifier string) string {
+	quote := "\""
+	if SqlDialectMysql == self.dialect {
+		quote = "`"
+	}
+
+	parts := strings.Split(identifier, ".")
+	for index, part := range parts {
+		if "*" == part {
+			continue
+		}
+		parts[index] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+func sqlBuilderCreate(self *Sql, statement string, table string) *SqlBuilder {
+	return &SqlBuilder{
+		sql:       self,
+		statement: statement,
+		table:     table,
+		limit:     -1,
+	}
+}
+
+// SqlSelect creates a SELECT query builder, columns are quoted identifiers,
+// see SqlBuilderSelectExpression to select expressions.
+//
+// Queries are built with SqlBuilderBuild, placeholders are written as "?",
+// so that queries plug directly into SqlExecute and SqlFind,
+// which rewrite them according to the dialect.
+func SqlSelect(self *Sql, columns ...string) *SqlBuilder {
+	builder := sqlBuilderCreate(self, "SELECT", "")
+	for _, column := range columns {
+		builder.selects = append(builder.selects, sqlBuilderClause{text: SqlQuoteIdentifier(self, column)})
+	}
+	return builder
+}
+
+// SqlInsert creates an INSERT query builder, see SqlBuilderColumns and SqlBuilderValues.
+func SqlInsert(self *Sql, table string) *SqlBuilder {
+	return sqlBuilderCreate(self, "INSERT", table)
+}
+
+// SqlUpdate creates an UPDATE query builder, see SqlBuilderSet.
+func SqlUpdate(self *Sql, table string) *SqlBuilder {
+	return sqlBuilderCreate(self, "UPDATE", table)
+}
+
+// SqlDelete creates a DELETE query builder.
+func SqlDelete(self *Sql, table string) *SqlBuilder {
+	return sqlBuilderCreate(self, "DELETE", table)
+}
+
+// SqlBuilderSelectExpression selects an expression, like "COUNT(*) AS total",
+// the expression is not quoted.
+func SqlBuilderSelectExpression(self *SqlBuilder, expression string, props ...any) {
+	self.selects = append(self.selects, sqlBuilderClause{text: expression, props: props})
+}
+
+// SqlBuilderFrom sets the table to select from.
+func SqlBuilderFrom(self *SqlBuilder, table string) {
+	self.table = table
+}
+
+// SqlBuilderJoin joins a table, on is a condition like "posts.user_id = users.id".
+func SqlBuilderJoin(self *SqlBuilder, table string, on string, props ...any) {
+	sqlBuilderJoin(self, "JOIN", table, on, props)
+}
+
+// SqlBuilderLeftJoin left joins a table, on is a condition like "posts.user_id = users.id".
+func SqlBuilderLeftJoin(self *SqlBuilder, table string, on string, props ...any) {
+	sqlBuilderJoin(self, "LEFT JOIN", table, on, props)
+}
+
+func sqlBuilderJoin(self *SqlBuilder, kind string, table string, on string, props []any) {
+	self.joins = append(self.joins, sqlBuilderClause{
+		text:  fmt.Sprintf("%s %s ON %s", kind, SqlQuoteIdentifier(self.sql, table), on),
+		props: props,
+	})
+}
+
+// SqlBuilderWhere adds a condition, like "age > ?", joined to the previous conditions with AND.
+//
+// Conditions are joined from left to right, so that
+// Where(a), Or(b), And(c) builds "((a) OR (b)) AND (c)".
+func SqlBuilderWhere(self *SqlBuilder, condition string, props ...any) {
+	sqlBuilderWhere(self, "AND", condition, props)
+}
+
+// SqlBuilderAnd adds a condition joined to the previous conditions with AND, see SqlBuilderWhere.
+func SqlBuilderAnd(self *SqlBuilder, condition string, props ...any) {
+	sqlBuilderWhere(self, "AND", condition, props)
+}
+
+// SqlBuilderOr adds a condition joined to the previous conditions with OR, see SqlBuilderWhere.
+func SqlBuilderOr(self *SqlBuilder, condition string, props ...any) {
+	sqlBuilderWhere(self, "OR", condition, props)
+}
+
+// SqlBuilderWhereIn adds a condition matching column against values, joined to the previous conditions with AND.
+//
+// No values match no rows.
+func SqlBuilderWhereIn(self *SqlBuilder, column string, values ...any) {
+	if 0 == len(values) {
+		sqlBuilderWhere(self, "AND", "1 = 0", nil)
+		return
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	sqlBuilderWhere(self, "AND", fmt.Sprintf("%s IN (%s)", SqlQuoteIdentifier(self.sql, column), placeholders), values)
+}
+
+func sqlBuilderWhere(self *SqlBuilder, connector string, condition string, props []any) {
+	self.where = append(self.where, sqlBuilderCondition{
+		connector: connector,
+		clause:    sqlBuilderClause{text: condition, props: props},
+	})
+}
+
+// SqlBuilderOrderBy orders rows by column, ascending.
+func SqlBuilderOrderBy(self *SqlBuilder, column string) {
+	self.orderBy = append(self.orderBy, SqlQuoteIdentifier(self.sql, column)+" ASC")
+}
+
+// SqlBuilderOrderByDescending orders rows by column, descending.
+func SqlBuilderOrderByDescending(self *SqlBuilder, column string) {
+	self.orderBy = append(self.orderBy, SqlQuoteIdentifier(self.sql, column)+" DESC")
+}
+
+// SqlBuilderLimit limits the number of rows.
+func SqlBuilderLimit(self *SqlBuilder, limit int) {
+	self.limit = limit
+}
+
+// SqlBuilderOffset skips a number of rows.
+func SqlBuilderOffset(self *SqlBuilder, offset int) {
+	self.offset = offset
+}
+
+// SqlBuilderColumns sets the columns to insert, see SqlBuilderValues.
+func SqlBuilderColumns(self *SqlBuilder, columns ...string) {
+	self.columns = columns
+}
+
+// SqlBuilderValues adds a row to insert, values are matched to the columns in order.
+func SqlBuilderValues(self *SqlBuilder, values ...any) {
+	self.rows = append(self.rows, values)
+}
+
+// SqlBuilderSet sets the value of a column to update.
+//
+// When inserting a single row, columns can also be set one by one instead of using SqlBuilderColumns and SqlBuilderValues.
+func SqlBuilderSet(self *SqlBuilder, column string, value any) {
+	self.sets = append(self.sets, sqlBuilderAssignment{column: column, value: value})
+}
+
+// SqlBuilderReturning returns columns of the inserted, updated or deleted rows.
+//
+// It is supported by SqlDialectPostgresql and SqlDialectSqlite, use SqlBuilderFind to read the rows.
+func SqlBuilderReturning(self *SqlBuilder, columns ...string) {
+	self.returning = append(self.returning, columns...)
+}
+
+// sqlBuilderIdentifiers quotes and joins identifiers.
+func sqlBuilderIdentifiers(self *SqlBuilder, identifiers []string) string {
+	quoted := make([]string, len(identifiers))
+	for index, identifier := range identifiers {
+		quoted[index] = SqlQuoteIdentifier(self.sql, identifier)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// SqlBuilderBuild builds the query and its parameters.
+func SqlBuilderBuild(self *SqlBuilder) (query string, props []any, err error) {
+	if "" == self.table {
+		return "", nil, errors.New("query has no table")
+	}
+
+	if len(self.returning) > 0 && ("SELECT" == self.statement || SqlDialectMysql == self.sql.dialect) {
+		return "", nil, fmt.Errorf("RETURNING is not supported by %s queries of this dialect", self.statement)
+	}
+
+	if len(self.joins) > 0 && "SELECT" != self.statement {
+		return "", nil, fmt.Errorf("joins are not supported by %s queries", self.statement)
+	}
+
+	var builder strings.Builder
+	table := SqlQuoteIdentifier(self.sql, self.table)
+
+	switch self.statement {
+	case "SELECT":
+		builder.WriteString("SELECT ")
+		if 0 == len(self.selects) {
+			builder.WriteString("*")
+		}
+		for index, clause := range self.selects {
+			if index > 0 {
+				builder.WriteString(", ")
+			}
+			builder.WriteString(clause.text)
+			props = append(props, clause.props...)
+		}
+		builder.WriteString(" FROM " + table)
+		for _, clause := range self.joins {
+			builder.WriteString(" " + clause.text)
+			props = append(props, clause.props...)
+		}
+
+	case "INSERT":
+		columns, rows := self.columns, self.rows
+		if 0 == len(rows) && len(self.sets) > 0 {
+			columns = nil
+			row := make([]any, 0, len(self.sets))
+			for _, assignment := range self.sets {
+				columns = append(columns, assignment.column)
+				row = append(row, assignment.value)
+			}
+			rows = [][]any{row}
+		}
+
+		if 0 == len(rows) {
+			return "", nil, errors.New("INSERT query has no values")
+		}
+
+		placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+		builder.WriteString(fmt.Sprintf("INSERT INTO %s (%s) VALUES ", table, sqlBuilderIdentifiers(self, columns)))
+		for index, row := range rows {
+			if len(row) != len(columns) {
+				return "", nil, fmt.Errorf("INSERT query has %d columns, but row %d has %d values", len(columns), index, len(row))
+			}
+			if index > 0 {
+				builder.WriteString(", ")
+			}
+			builder.WriteString(placeholders)
+			props = append(props, row...)
+		}
+
+	case "UPDATE":
+		if 0 == len(self.sets) {
+			return "", nil, errors.New("UPDATE query has no values")
+		}
+
+		builder.WriteString(fmt.Sprintf("UPDATE %s SET ", table))
+		for index, assignment := range self.sets {
+			if index > 0 {
+				builder.WriteString(", ")
+			}
+			builder.WriteString(SqlQuoteIdentifier(self.sql, assignment.column) + " = ?")
+			props = append(props, assignment.value)
+		}
+
+	case "DELETE":
+		builder.WriteString("DELETE FROM " + table)
+	}
+
+	if len(self.where) > 0 && "INSERT" != self.statement {
+		condition := ""
+		for index, item := range self.where {
+			switch {
+			case 0 == index:
+				condition = "(" + item.clause.text + ")"
+			case item.connector != self.where[index-1].connector && index > 1:
+				condition = "(" + condition + ") " + item.connector + " (" + item.clause.text + ")"
+			default:
+				condition += " " + item.connector + " (" + item.clause.text + ")"
+			}
+			props = append(props, item.clause.props...)
+		}
+		builder.WriteString(" WHERE " + condition)
+	}
+
+	if "SELECT" == self.statement {
+		if len(self.orderBy) > 0 {
+			builder.WriteString(" ORDER BY " + strings.Join(self.orderBy, ", "))
+		}
+
+		if self.limit >= 0 {
+			builder.WriteString(" LIMIT " + strconv.Itoa(self.limit))
+		}
+
+		if self.offset > 0 {
+			// Mysql and Sqlite require a limit along with the offset.
+			if self.limit < 0 && SqlDialectMysql == self.sql.dialect {
+				builder.WriteString(" LIMIT 18446744073709551615")
+			}
+			if self.limit < 0 && SqlDialectSqlite == self.sql.dialect {
+				builder.WriteString(" LIMIT -1")
+			}
+			builder.WriteString(" OFFSET " + strconv.Itoa(self.offset))
+		}
+	}
+
+	if len(self.returning) > 0 {
+		builder.WriteString(" RETURNING " + sqlBuilderIdentifiers(self, self.returning))
+	}
+
+	return builder.String(), props, nil
+}
+
+// SqlBuilderExecute builds and executes the query, see SqlExecute.
+//
+// Errors are sent to the notifier, in which case it returns nil, see SqlBuilderTryExecute.
+func SqlBuilderExecute(self *SqlBuilder) *sql.Result {
+	query, props, buildError := SqlBuilderBuild(self)
+	if buildError != nil {
+		NotifierSendError(self.sql.notifier, buildError)
+		return nil
+	}
+	return SqlExecute(self.sql, query, props...)
+}
+
+// SqlBuilderTryExecute builds and executes the query, see SqlTryExecute.
+func SqlBuilderTryExecute(self *SqlBuilder) (sql.Result, error) {
+	query, props, buildError := SqlBuilderBuild(self)
+	if buildError != nil {
+		return nil, buildError
+	}
+	return SqlTryExecute(self.sql, query, props...)
+}
+
+// SqlBuilderFind builds and executes the query, see SqlFind.
+//
+// Errors are sent to the notifier, see SqlBuilderTryFind.
+func SqlBuilderFind(self *SqlBuilder) (next func(dest ...any) bool, close func()) {
+	query, props, buildError := SqlBuilderBuild(self)
+	if buildError != nil {
+		NotifierSendError(self.sql.notifier, buildError)
+		return sqlFindNextFallback, sqlFindCloseFallback
+	}
+	return SqlFind(self.sql, query, props...)
+}
+
+// SqlBuilderTryFind builds and executes the query, see SqlTryFind.
+func SqlBuilderTryFind(self *SqlBuilder) (*SqlRows, error) {
+	query, props, buildError := SqlBuilderBuild(self)
+	if buildError != nil {
+		return nil, buildError
+	}
+	return SqlTryFind(self.sql, query, props...)
+}
@@ -0,0 +1,114 @@
+package frizzante
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSqlBuilderBuild(test *testing.T) {
+	mysql := SqlCreate()
+	postgresql := SqlCreate()
+	SqlWithDialect(postgresql, SqlDialectPostgresql)
+
+	query := SqlSelect(mysql, "users.id", "name")
+	SqlBuilderSelectExpression(query, "COUNT(posts.id) AS total")
+	SqlBuilderFrom(query, "users")
+	SqlBuilderLeftJoin(query, "posts", "posts.user_id = users.id AND posts.draft = ?", false)
+	SqlBuilderWhere(query, "age > ?", 18)
+	SqlBuilderOr(query, "name LIKE ?", "a%")
+	SqlBuilderWhereIn(query, "role", "admin", "editor")
+	SqlBuilderOrderByDescending(query, "name")
+	SqlBuilderLimit(query, 10)
+	SqlBuilderOffset(query, 20)
+
+	actual, props, buildError := SqlBuilderBuild(query)
+	if buildError != nil {
+		test.Fatal(buildError)
+	}
+
+	expected := "SELECT `users`.`id`, `name`, COUNT(posts.id) AS total FROM `users` LEFT JOIN `posts` ON posts.user_id = users.id AND posts.draft = ? WHERE ((age > ?) OR (name LIKE ?)) AND (`role` IN (?, ?)) ORDER BY `name` DESC LIMIT 10 OFFSET 20"
+	if expected != actual || !reflect.DeepEqual([]any{false, 18, "a%", "admin", "editor"}, props) {
+		test.Fatalf("query was expected to be `%s`, received `%s` with %v instead", expected, actual, props)
+	}
+
+	query = SqlUpdate(postgresql, "users")
+	SqlBuilderSet(query, "name", "test")
+	SqlBuilderWhere(query, "id = ?", 1)
+	SqlBuilderReturning(query, "id")
+
+	actual, props, buildError = SqlBuilderBuild(query)
+	if buildError != nil {
+		test.Fatal(buildError)
+	}
+
+	expected = `UPDATE "users" SET "name" = ? WHERE (id = ?) RETURNING "id"`
+	if expected != actual || !reflect.DeepEqual([]any{"test", 1}, props) {
+		test.Fatalf("query was expected to be `%s`, received `%s` with %v instead", expected, actual, props)
+	}
+
+	if quoted := SqlQuoteIdentifier(postgresql, `we"ird`); `"we""ird"` != quoted {
+		test.Fatalf("identifier was expected to be escaped, received %s instead", quoted)
+	}
+
+	query = SqlInsert(mysql, "users")
+	SqlBuilderReturning(query, "id")
+	if _, _, buildError = SqlBuilderBuild(query); nil == buildError {
+		test.Fatal("RETURNING was expected to fail on mysql")
+	}
+
+	query = SqlInsert(mysql, "users")
+	SqlBuilderColumns(query, "id", "name")
+	SqlBuilderValues(query, 1)
+	if _, _, buildError = SqlBuilderBuild(query); nil == buildError {
+		test.Fatal("rows with missing values were expected to fail")
+	}
+}
+
+func TestSqlBuilderFind(test *testing.T) {
+	sqlite := sqliteCreate(test)
+
+	if _, createError := SqlTryExecute(sqlite, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"); createError != nil {
+		test.Fatal(createError)
+	}
+
+	query := SqlInsert(sqlite, "users")
+	SqlBuilderColumns(query, "id", "name")
+	SqlBuilderValues(query, 1, "first")
+	SqlBuilderValues(query, 2, "second")
+	SqlBuilderValues(query, 3, "third")
+	if _, insertError := SqlBuilderTryExecute(query); insertError != nil {
+		test.Fatal(insertError)
+	}
+
+	query = SqlDelete(sqlite, "users")
+	SqlBuilderWhere(query, "id = ?", 3)
+	SqlBuilderReturning(query, "name")
+	rows, deleteError := SqlBuilderTryFind(query)
+	if deleteError != nil {
+		test.Fatal(deleteError)
+	}
+
+	var deleted string
+	if !SqlRowsNext(rows, &deleted) || "third" != deleted {
+		test.Fatalf("deleted row was expected to be returned, received %s instead", deleted)
+	}
+	_ = SqlRowsClose(rows)
+
+	query = SqlSelect(sqlite, "name")
+	SqlBuilderFrom(query, "users")
+	SqlBuilderOrderByDescending(query, "id")
+	SqlBuilderOffset(query, 1)
+
+	next, close := SqlBuilderFind(query)
+	defer close()
+
+	var names []string
+	var name string
+	for next(&name) {
+		names = append(names, name)
+	}
+
+	if !reflect.DeepEqual([]string{"first"}, names) {
+		test.Fatalf("names were expected to be [first], received %v instead", names)
+	}
+}
@@ -0,0 +1,231 @@
+package frizzante
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// sqlNamedProps finds the named parameters of a query, if props consist of a single map or struct.
+func sqlNamedProps(props []any) (lookup func(name string) (any, bool), named bool) {
+	if 1 != len(props) || nil == props[0] {
+		return nil, false
+	}
+
+	if _, isValuer := props[0].(driver.Valuer); isValuer {
+		return nil, false
+	}
+
+	value := reflect.ValueOf(props[0])
+	for reflect.Pointer == value.Kind() {
+		if value.IsNil() {
+			return nil, false
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Map:
+		if reflect.String != value.Type().Key().Kind() {
+			return nil, false
+		}
+		return func(name string) (any, bool) {
+			item := value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+			if !item.IsValid() {
+				return nil, false
+			}
+			return item.Interface(), true
+		}, true
+	case reflect.Struct:
+		if sqlIsScalar(value.Type()) {
+			return nil, false
+		}
+		fields := sqlStructFields(value.Type())
+		return func(name string) (any, bool) {
+			index, exists := fields[strings.ToLower(name)]
+			if !exists {
+				return nil, false
+			}
+			field, fieldError := value.FieldByIndexErr(index)
+			if fieldError != nil {
+				// Nil embedded pointers hold no values.
+				return nil, true
+			}
+			return field.Interface(), true
+		}, true
+	}
+
+	return nil, false
+}
+
+// sqlIsIdentifier checks if a character can be part of a parameter name.
+func sqlIsIdentifier(character byte, first bool) bool {
+	if '_' == character || ('a' <= character && character <= 'z') || ('A' <= character && character <= 'Z') {
+		return true
+	}
+	return !first && '0' <= character && character <= '9'
+}
+
+// sqlQueryRewrite rewrites the placeholders of a query according to the dialect.
+//
+// Placeholders are written as "?" regardless of the dialect, they're rewritten as "$1", "$2" and so on
+// for SqlDialectPostgresql, use "??" for a literal "?".
+//
+// When props consist of a single map or struct, placeholders can also be written as ":name",
+// in which case they're bound to the map item or the struct field with the same name, see SqlFindEach.
+//
+// Queries already using "$1", "$2" and so on are left untouched for SqlDialectPostgresql,
+// so that operators like "?", "?|" and "?&" can be used along with them.
+//
+// Placeholders within quotes and comments are left untouched.
+func sqlQueryRewrite(dialect SqlDialect, query string, props []any) (string, []any, error) {
+	lookup, named := sqlNamedProps(props)
+	if !named && !strings.Contains(query, "?") {
+		return query, props, nil
+	}
+
+	var builder strings.Builder
+	var bound []any
+	var placeholderError error
+	position := 0
+	placeholder := func() {
+		position++
+		if SqlDialectPostgresql == dialect {
+			builder.WriteString("$" + strconv.Itoa(position))
+			return
+		}
+		builder.WriteByte('?')
+	}
+
+	length := len(query)
+	for index := 0; index < length; index++ {
+		character := query[index]
+		switch {
+		case '\'' == character || '"' == character || '`' == character:
+			end := index + 1
+			for end < length {
+				if '\\' == query[end] && SqlDialectMysql == dialect && '`' != character {
+					end += 2
+					continue
+				}
+				if character == query[end] {
+					if end+1 < length && character == query[end+1] {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, length)
+			builder.WriteString(query[index:end])
+			index = end - 1
+
+		case ('-' == character && index+1 < length && '-' == query[index+1]) || ('#' == character && SqlDialectMysql == dialect):
+			end := strings.IndexByte(query[index:], '\n')
+			if -1 == end {
+				end = length - index
+			}
+			builder.WriteString(query[index : index+end])
+			index += end - 1
+
+		case '/' == character && index+1 < length && '*' == query[index+1]:
+			end := strings.Index(query[index+2:], "*/")
+			if -1 == end {
+				end = length
+			} else {
+				end = index + 2 + end + 2
+			}
+			builder.WriteString(query[index:end])
+			index = end - 1
+
+		case '$' == character && SqlDialectPostgresql == dialect && (index+1 < length && ('$' == query[index+1] || sqlIsIdentifier(query[index+1], true))):
+			// Dollar quoted strings, like $$text$$ or $tag$text$tag$.
+			tagEnd := index + 1
+			for tagEnd < length && sqlIsIdentifier(query[tagEnd], false) {
+				tagEnd++
+			}
+			if tagEnd >= length || '$' != query[tagEnd] {
+				builder.WriteByte(character)
+				continue
+			}
+			tag := query[index : tagEnd+1]
+			end := strings.Index(query[tagEnd+1:], tag)
+			if -1 == end {
+				end = length
+			} else {
+				end = tagEnd + 1 + end + len(tag)
+			}
+			builder.WriteString(query[index:end])
+			index = end - 1
+
+		case '$' == character && SqlDialectPostgresql == dialect && index+1 < length && '0' <= query[index+1] && query[index+1] <= '9':
+			// The query is already written with the placeholders of the dialect.
+			return query, props, nil
+
+		case '?' == character:
+			if index+1 < length && '?' == query[index+1] {
+				builder.WriteByte('?')
+				index++
+				continue
+			}
+			// Errors are reported only once the whole query is read,
+			// because it may turn out to be already written with numbered placeholders.
+			if named {
+				if nil == placeholderError {
+					placeholderError = fmt.Errorf("positional placeholder at offset %d cannot be bound to named parameters", index)
+				}
+				builder.WriteByte(character)
+				continue
+			}
+			if position >= len(props) {
+				if nil == placeholderError {
+					placeholderError = fmt.Errorf("query has more placeholders than the %d parameters received", len(props))
+				}
+				builder.WriteByte(character)
+				continue
+			}
+			bound = append(bound, props[position])
+			placeholder()
+
+		case ':' == character && named:
+			if index+1 < length && ':' == query[index+1] {
+				// Type casts, like value::text.
+				builder.WriteString("::")
+				index++
+				continue
+			}
+			end := index + 1
+			for end < length && sqlIsIdentifier(query[end], end == index+1) {
+				end++
+			}
+			if end == index+1 {
+				builder.WriteByte(character)
+				continue
+			}
+			name := query[index+1 : end]
+			value, exists := lookup(name)
+			if !exists {
+				return "", nil, fmt.Errorf("named parameter `%s` has no value", name)
+			}
+			bound = append(bound, value)
+			placeholder()
+			index = end - 1
+
+		default:
+			builder.WriteByte(character)
+		}
+	}
+
+	if placeholderError != nil {
+		return "", nil, placeholderError
+	}
+
+	if !named && position < len(props) {
+		return "", nil, fmt.Errorf("query has %d placeholders, but received %d parameters", position, len(props))
+	}
+
+	return builder.String(), bound, nil
+}
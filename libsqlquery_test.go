@@ -0,0 +1,106 @@
+package frizzante
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSqlQueryRewrite(test *testing.T) {
+	query, props, rewriteError := sqlQueryRewrite(
+		SqlDialectPostgresql,
+		"SELECT * FROM t WHERE a = ? AND b = '?''?' AND c = ?? -- ?\nAND d = ? /* ? */ AND e = $$?$$",
+		[]any{1, 2},
+	)
+	if rewriteError != nil {
+		test.Fatal(rewriteError)
+	}
+
+	expected := "SELECT * FROM t WHERE a = $1 AND b = '?''?' AND c = ? -- ?\nAND d = $2 /* ? */ AND e = $$?$$"
+	if expected != query || !reflect.DeepEqual([]any{1, 2}, props) {
+		test.Fatalf("query was expected to be `%s`, received `%s` with %v instead", expected, query, props)
+	}
+
+	query, props, rewriteError = sqlQueryRewrite(
+		SqlDialectPostgresql,
+		"SELECT :name::text, :age, ':name'",
+		[]any{map[string]any{"name": "test", "age": 30}},
+	)
+	if rewriteError != nil {
+		test.Fatal(rewriteError)
+	}
+
+	expected = "SELECT $1::text, $2, ':name'"
+	if expected != query || !reflect.DeepEqual([]any{"test", 30}, props) {
+		test.Fatalf("query was expected to be `%s`, received `%s` with %v instead", expected, query, props)
+	}
+
+	query, props, rewriteError = sqlQueryRewrite(
+		SqlDialectMysql,
+		"SELECT * FROM t WHERE name = :name AND id = :Id",
+		[]any{struct {
+			Id   int
+			Name string `db:"name"`
+		}{Id: 1, Name: "test"}},
+	)
+	if rewriteError != nil {
+		test.Fatal(rewriteError)
+	}
+
+	expected = "SELECT * FROM t WHERE name = ? AND id = ?"
+	if expected != query || !reflect.DeepEqual([]any{"test", 1}, props) {
+		test.Fatalf("query was expected to be `%s`, received `%s` with %v instead", expected, query, props)
+	}
+
+	query, props, rewriteError = sqlQueryRewrite(
+		SqlDialectPostgresql,
+		"SELECT * FROM t WHERE tags ? $1 AND tags ?| $2 AND tags ?& $2",
+		[]any{"a", []string{"b", "c"}},
+	)
+	if rewriteError != nil {
+		test.Fatal(rewriteError)
+	}
+
+	expected = "SELECT * FROM t WHERE tags ? $1 AND tags ?| $2 AND tags ?& $2"
+	if expected != query || !reflect.DeepEqual([]any{"a", []string{"b", "c"}}, props) {
+		test.Fatalf("query was expected to be `%s`, received `%s` with %v instead", expected, query, props)
+	}
+
+	query, props, rewriteError = sqlQueryRewrite(SqlDialectMysql, "SELECT * FROM t WHERE a = ? # b = ?\nAND c = ?", []any{1, 2})
+	if rewriteError != nil {
+		test.Fatal(rewriteError)
+	}
+
+	expected = "SELECT * FROM t WHERE a = ? # b = ?\nAND c = ?"
+	if expected != query || !reflect.DeepEqual([]any{1, 2}, props) {
+		test.Fatalf("query was expected to be `%s`, received `%s` with %v instead", expected, query, props)
+	}
+
+	_, _, rewriteError = sqlQueryRewrite(SqlDialectMysql, "SELECT :missing", []any{map[string]any{}})
+	if nil == rewriteError {
+		test.Fatal("missing named parameters were expected to fail")
+	}
+
+	_, _, rewriteError = sqlQueryRewrite(SqlDialectMysql, "SELECT ?, ?", []any{1})
+	if nil == rewriteError {
+		test.Fatal("missing positional parameters were expected to fail")
+	}
+}
+
+func TestSqlDialectSqlite(test *testing.T) {
+	sqlite := sqliteCreate(test)
+
+	_, createError := SqlTryExecute(sqlite, "CREATE TABLE fruits (name TEXT NOT NULL, quantity INTEGER NOT NULL)")
+	if createError != nil {
+		test.Fatal(createError)
+	}
+
+	_, insertError := SqlTryExecute(sqlite, "INSERT INTO fruits (name, quantity) VALUES (:name, :quantity)", map[string]any{"name": "apples", "quantity": 3})
+	if insertError != nil {
+		test.Fatal(insertError)
+	}
+
+	quantity, findError := SqlFindOne[int](sqlite, "SELECT quantity FROM fruits WHERE name = ?", "apples")
+	if findError != nil || 3 != quantity {
+		test.Fatalf("quantity was expected to be 3, received %d and error %v instead", quantity, findError)
+	}
+}
@@ -0,0 +1,308 @@
+package frizzante
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sqlStructCache caches the fields of scanned struct types, by column name.
+var sqlStructCache sync.Map
+
+var sqlScannerType = reflect.TypeFor[sql.Scanner]()
+var sqlTimeType = reflect.TypeFor[time.Time]()
+
+// sqlStructFields finds the index of the field of each column of a struct type,
+// column names are lower case.
+//
+// Columns are mapped to fields by their `db` tag, or by their name when they have no tag,
+// fields tagged with `db:"-"` are ignored.
+//
+// Fields of embedded structs are mapped as if they belonged to the embedding struct,
+// unless a less deeply embedded field is mapped to the same column.
+// Columns mapped to more than one field at the same depth are ambiguous and left unmapped.
+// Unexported embedded struct pointers are ignored, because they can't be allocated.
+func sqlStructFields(structType reflect.Type) map[string][]int {
+	cached, exists := sqlStructCache.Load(structType)
+	if exists {
+		return cached.(map[string][]int)
+	}
+
+	cached, _ = sqlStructCache.LoadOrStore(structType, sqlStructFieldsCollect(structType))
+	return cached.(map[string][]int)
+}
+
+// sqlStructFieldsCollect collects the fields of a struct type, breadth first.
+//
+// Fields of shallower structs win over those of more deeply embedded ones,
+// columns mapped to more than one field at the same depth are ambiguous and dropped.
+func sqlStructFieldsCollect(structType reflect.Type) map[string][]int {
+	type embedding struct {
+		structType reflect.Type
+		prefix     []int
+	}
+
+	fields := map[string][]int{}
+	ambiguous := map[string]bool{}
+	visited := map[reflect.Type]bool{}
+	current := []embedding{{structType: structType}}
+	for len(current) > 0 {
+		var next []embedding
+		claims := map[string][][]int{}
+		for _, level := range current {
+			if visited[level.structType] {
+				continue
+			}
+
+			for index := range level.structType.NumField() {
+				field := level.structType.Field(index)
+				tag := field.Tag.Get("db")
+				if "-" == tag {
+					continue
+				}
+
+				fieldIndex := append(append([]int{}, level.prefix...), index)
+				fieldType := field.Type
+				if reflect.Pointer == fieldType.Kind() {
+					if field.Anonymous && !field.IsExported() {
+						continue
+					}
+					fieldType = fieldType.Elem()
+				}
+
+				if field.Anonymous && "" == tag && reflect.Struct == fieldType.Kind() && !sqlIsScalar(fieldType) {
+					next = append(next, embedding{structType: fieldType, prefix: fieldIndex})
+					continue
+				}
+
+				if !field.IsExported() {
+					continue
+				}
+
+				name := tag
+				if "" == name {
+					name = field.Name
+				}
+				name = strings.ToLower(name)
+				claims[name] = append(claims[name], fieldIndex)
+			}
+		}
+
+		// Types are marked only once the whole depth is done,
+		// so that a type embedded twice at the same depth makes its columns ambiguous.
+		for _, level := range current {
+			visited[level.structType] = true
+		}
+
+		for name, indexes := range claims {
+			if _, exists := fields[name]; exists || ambiguous[name] {
+				continue
+			}
+
+			if len(indexes) > 1 {
+				ambiguous[name] = true
+				continue
+			}
+
+			fields[name] = indexes[0]
+		}
+
+		current = next
+	}
+
+	return fields
+}
+
+// sqlIsScalar checks if values of a type are scanned from a single column, as a whole.
+func sqlIsScalar(valueType reflect.Type) bool {
+	if reflect.Struct != valueType.Kind() {
+		return true
+	}
+
+	return sqlTimeType == valueType || reflect.PointerTo(valueType).Implements(sqlScannerType)
+}
+
+// sqlFieldByIndex finds a nested field, allocating nil embedded pointers along the way.
+func sqlFieldByIndex(value reflect.Value, index []int) reflect.Value {
+	for position, fieldIndex := range index {
+		if position > 0 && reflect.Pointer == value.Kind() {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(fieldIndex)
+	}
+	return value
+}
+
+// sqlScanDestinations finds where to scan each column of a row into value.
+func sqlScanDestinations(value reflect.Value, columns []string, indexes [][]int) []any {
+	dest := make([]any, len(columns))
+	if nil == indexes {
+		dest[0] = value.Addr().Interface()
+		return dest
+	}
+
+	for position, index := range indexes {
+		dest[position] = sqlFieldByIndex(value, index).Addr().Interface()
+	}
+	return dest
+}
+
+// sqlScanPlan finds the index of the field of each column of a type.
+//
+// It returns nil indexes if the type is scanned from a single column.
+func sqlScanPlan(valueType reflect.Type, columns []string) (indexes [][]int, err error) {
+	if sqlIsScalar(valueType) {
+		if 1 != len(columns) {
+			return nil, fmt.Errorf("%s can only be scanned from 1 column, received %d columns instead", valueType, len(columns))
+		}
+		return nil, nil
+	}
+
+	fields := sqlStructFields(valueType)
+	indexes = make([][]int, len(columns))
+	for position, column := range columns {
+		index, exists := fields[strings.ToLower(column)]
+		if !exists {
+			return nil, fmt.Errorf("column `%s` has no matching field in %s", column, valueType)
+		}
+		indexes[position] = index
+	}
+
+	return indexes, nil
+}
+
+// SqlFindEach executes a sql query that returns rows, typically a SELECT query,
+// and iterates the rows as values of type T.
+//
+// If T is a struct, or a pointer to a struct, columns are mapped to its fields by their `db` tag, or by their name,
+// otherwise T is scanned from a single column.
+//
+// Null columns can be scanned into pointer or sql.Null fields.
+//
+// The iteration stops at the first error, which is yielded along with a zero value.
+func SqlFindEach[T any](self *Sql, query string, props ...any) iter.Seq2[T, error] {
+	return SqlFindEachWithContext[T](self, context.Background(), query, props...)
+}
+
+// SqlFindEachWithContext works like SqlFindEach, except the query stops as soon as ctx is done.
+func SqlFindEachWithContext[T any](self *Sql, ctx context.Context, query string, props ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		ctx, span := sqlSpanStart(self, ctx, "sql find", query)
+		defer SpanEnd(span)
+
+		query, props, rewriteError := sqlQueryRewrite(self.dialect, query, props)
+		if rewriteError != nil {
+			SpanWithError(span, rewriteError)
+			yield(zero, rewriteError)
+			return
+		}
+
+		statement, release, statementError := sqlPrepare(self, ctx, query)
+		if statementError != nil {
+			SpanWithError(span, statementError)
+			yield(zero, statementError)
+			return
+		}
+		defer release()
+
+		rows, queryError := statement.QueryContext(ctx, props...)
+		if queryError != nil {
+			SpanWithError(span, queryError)
+			sqlStatementInvalidate(self, query, statement, queryError)
+			yield(zero, queryError)
+			return
+		}
+		defer rows.Close()
+
+		columns, columnsError := rows.Columns()
+		if columnsError != nil {
+			yield(zero, columnsError)
+			return
+		}
+
+		// Structs behind pointers are allocated for each row and scanned as structs.
+		valueType := reflect.TypeFor[T]()
+		pointer := reflect.Pointer == valueType.Kind() && !sqlIsScalar(valueType.Elem())
+		if pointer {
+			valueType = valueType.Elem()
+		}
+
+		indexes, planError := sqlScanPlan(valueType, columns)
+		if planError != nil {
+			yield(zero, planError)
+			return
+		}
+
+		for rows.Next() {
+			var value T
+			target := reflect.ValueOf(&value).Elem()
+			if pointer {
+				target.Set(reflect.New(valueType))
+				target = target.Elem()
+			}
+
+			scanError := rows.Scan(sqlScanDestinations(target, columns, indexes)...)
+			if scanError != nil {
+				SpanWithError(span, scanError)
+				yield(zero, scanError)
+				return
+			}
+
+			if !yield(value, nil) {
+				return
+			}
+		}
+
+		rowsError := rows.Err()
+		if rowsError != nil {
+			SpanWithError(span, rowsError)
+			yield(zero, rowsError)
+		}
+	}
+}
+
+// SqlFindAll executes a sql query that returns rows, typically a SELECT query,
+// and scans all rows as values of type T, see SqlFindEach.
+func SqlFindAll[T any](self *Sql, query string, props ...any) ([]T, error) {
+	return SqlFindAllWithContext[T](self, context.Background(), query, props...)
+}
+
+// SqlFindAllWithContext works like SqlFindAll, except the query stops as soon as ctx is done.
+func SqlFindAllWithContext[T any](self *Sql, ctx context.Context, query string, props ...any) ([]T, error) {
+	values := []T{}
+	for value, err := range SqlFindEachWithContext[T](self, ctx, query, props...) {
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// SqlFindOne executes a sql query that returns rows, typically a SELECT query,
+// and scans the first row as a value of type T, see SqlFindEach.
+//
+// It returns sql.ErrNoRows if the query returns no rows.
+func SqlFindOne[T any](self *Sql, query string, props ...any) (T, error) {
+	return SqlFindOneWithContext[T](self, context.Background(), query, props...)
+}
+
+// SqlFindOneWithContext works like SqlFindOne, except the query stops as soon as ctx is done.
+func SqlFindOneWithContext[T any](self *Sql, ctx context.Context, query string, props ...any) (T, error) {
+	for value, err := range SqlFindEachWithContext[T](self, ctx, query, props...) {
+		return value, err
+	}
+
+	var zero T
+	return zero, sql.ErrNoRows
+}
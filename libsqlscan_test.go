@@ -0,0 +1,131 @@
+package frizzante
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+type sqlScanTestTimestamps struct {
+	CreatedAt int64 `db:"created_at"`
+}
+
+type sqlScanTestFruit struct {
+	sqlScanTestTimestamps
+	Id       int64          `db:"id"`
+	Name     string         `db:"name"`
+	Color    *string        `db:"color"`
+	Origin   sql.NullString `db:"origin"`
+	Ignored  string         `db:"-"`
+	Quantity int
+}
+
+type sqlScanTestLabel struct {
+	Label string `db:"label"`
+}
+
+type sqlScanTestNamed struct {
+	*sqlScanTestLabel
+	Name string `db:"name"`
+}
+
+type sqlScanTestDeep struct {
+	Name string `db:"name"`
+}
+
+type sqlScanTestOuter struct {
+	sqlScanTestDeep
+	Label string `db:"label"`
+}
+
+type sqlScanTestShallow struct {
+	Name  string `db:"name"`
+	Label string `db:"label"`
+}
+
+type sqlScanTestShadowed struct {
+	sqlScanTestOuter
+	sqlScanTestShallow
+}
+
+func TestSqlStructFields(test *testing.T) {
+	fields := sqlStructFields(reflect.TypeFor[sqlScanTestShadowed]())
+
+	if !slices.Equal([]int{1, 0}, fields["name"]) {
+		test.Fatalf("shallower fields were expected to shadow deeper ones, received %v instead", fields["name"])
+	}
+
+	if _, exists := fields["label"]; exists {
+		test.Fatalf("fields at the same depth were expected to be ambiguous, received %v instead", fields["label"])
+	}
+}
+
+func TestSqlFindAll(test *testing.T) {
+	sqlite := sqliteCreate(test)
+	SqlExecute(sqlite, "CREATE TABLE fruits (id INTEGER PRIMARY KEY, name TEXT NOT NULL, color TEXT, origin TEXT, quantity INTEGER NOT NULL, created_at INTEGER NOT NULL)")
+	SqlExecute(sqlite, "INSERT INTO fruits (name, color, origin, quantity, created_at) VALUES ('apples', 'red', NULL, 3, 100), ('pears', NULL, 'italy', 5, 200)")
+
+	fruits, findError := SqlFindAll[sqlScanTestFruit](sqlite, "SELECT * FROM fruits ORDER BY id")
+	if findError != nil {
+		test.Fatal(findError)
+	}
+
+	if 2 != len(fruits) {
+		test.Fatalf("2 fruits were expected, received %d instead", len(fruits))
+	}
+
+	apples := fruits[0]
+	if "apples" != apples.Name || nil == apples.Color || "red" != *apples.Color || apples.Origin.Valid || 3 != apples.Quantity || 100 != apples.CreatedAt {
+		test.Fatalf("apples were not scanned as expected, received %+v instead", apples)
+	}
+
+	pears := fruits[1]
+	if nil != pears.Color || !pears.Origin.Valid || "italy" != pears.Origin.String {
+		test.Fatalf("null columns were not scanned as expected, received %+v instead", pears)
+	}
+
+	count, countError := SqlFindOne[int](sqlite, "SELECT COUNT(*) FROM fruits")
+	if countError != nil || 2 != count {
+		test.Fatalf("count was expected to be 2, received %d instead", count)
+	}
+
+	_, noRowsError := SqlFindOne[sqlScanTestFruit](sqlite, "SELECT * FROM fruits WHERE name = ?", "plums")
+	if !errors.Is(noRowsError, sql.ErrNoRows) {
+		test.Fatalf("sql.ErrNoRows was expected, received %v instead", noRowsError)
+	}
+
+	_, columnError := SqlFindAll[sqlScanTestFruit](sqlite, "SELECT name, 1 AS unknown FROM fruits")
+	if nil == columnError {
+		test.Fatal("unknown columns were expected to fail")
+	}
+
+	var names []string
+	for name, err := range SqlFindEach[string](sqlite, "SELECT name FROM fruits ORDER BY id") {
+		if err != nil {
+			test.Fatal(err)
+		}
+		names = append(names, name)
+		break
+	}
+
+	if 1 != len(names) || "apples" != names[0] {
+		test.Fatalf("iteration was expected to stop after apples, received %v instead", names)
+	}
+
+	pointer, pointerError := SqlFindOne[*sqlScanTestFruit](sqlite, "SELECT * FROM fruits WHERE name = ?", "pears")
+	if pointerError != nil || nil == pointer || "pears" != pointer.Name || 200 != pointer.CreatedAt {
+		test.Fatalf("pears were expected to be scanned through a pointer, received %+v and error %v instead", pointer, pointerError)
+	}
+
+	named, namedError := SqlFindAll[sqlScanTestNamed](sqlite, "SELECT name FROM fruits ORDER BY id")
+	if namedError != nil || 2 != len(named) || "apples" != named[0].Name || nil != named[0].sqlScanTestLabel {
+		test.Fatalf("unexported embedded pointers were expected to be ignored, received %+v and error %v instead", named, namedError)
+	}
+
+	_, queryError := SqlFindAll[string](sqlite, "SELECT name FROM vegetables")
+	if nil == queryError {
+		test.Fatal("query on a missing table was expected to fail")
+	}
+}
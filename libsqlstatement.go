@@ -0,0 +1,192 @@
+package frizzante
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+type sqlStatementEntry struct {
+	query      string
+	statement  *sql.Stmt
+	references int
+	removed    bool
+}
+
+type sqlStatementCache struct {
+	lock    sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	hits    uint64
+	misses  uint64
+}
+
+// sqlStatementCacheCreate creates a cache of prepared statements,
+// evicting the least recently used statements past size.
+func sqlStatementCacheCreate(size int) *sqlStatementCache {
+	return &sqlStatementCache{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// sqlStatementCacheClear removes all cached statements, see sqlStatementRemove.
+func sqlStatementCacheClear(self *Sql) {
+	cache := self.statements
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	for cache.order.Len() > 0 {
+		sqlStatementRemove(self, cache.order.Back())
+	}
+}
+
+// sqlStatementCacheEvict removes the least recently used statements past the size of the cache.
+//
+// It must be invoked while holding the lock.
+func sqlStatementCacheEvict(self *Sql) {
+	cache := self.statements
+	for cache.order.Len() > cache.size {
+		sqlStatementRemove(self, cache.order.Back())
+	}
+}
+
+// sqlStatementRemove removes a statement from the cache.
+//
+// The statement is closed right away if no query is using it,
+// otherwise it's closed as soon as the last query using it releases it.
+//
+// It must be invoked while holding the lock.
+func sqlStatementRemove(self *Sql, element *list.Element) {
+	cache := self.statements
+	entry := element.Value.(*sqlStatementEntry)
+	cache.order.Remove(element)
+	delete(cache.entries, entry.query)
+	entry.removed = true
+
+	if 0 == entry.references {
+		closeError := entry.statement.Close()
+		if closeError != nil {
+			NotifierSendError(self.notifier, closeError)
+		}
+	}
+}
+
+// sqlStatementAcquire marks a cached statement as in use, until release is invoked.
+//
+// It must be invoked while holding the lock.
+func sqlStatementAcquire(self *Sql, element *list.Element) (statement *sql.Stmt, release func() error) {
+	entry := element.Value.(*sqlStatementEntry)
+	entry.references++
+	self.statements.order.MoveToFront(element)
+
+	return entry.statement, func() error {
+		self.statements.lock.Lock()
+		defer self.statements.lock.Unlock()
+		entry.references--
+		if entry.removed && 0 == entry.references {
+			return entry.statement.Close()
+		}
+		return nil
+	}
+}
+
+// sqlPrepare finds the prepared statement of a query, preparing and caching it if needed.
+//
+// Release must be invoked once the statement is no longer used,
+// statements that are not cached are closed by it.
+func sqlPrepare(self *Sql, ctx context.Context, query string) (statement *sql.Stmt, release func() error, err error) {
+	cache := self.statements
+	cache.lock.Lock()
+	if cache.size <= 0 {
+		cache.lock.Unlock()
+		statement, err = self.database.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		return statement, statement.Close, nil
+	}
+
+	element, exists := cache.entries[query]
+	if exists {
+		cache.hits++
+		statement, release = sqlStatementAcquire(self, element)
+		cache.lock.Unlock()
+		return statement, release, nil
+	}
+	cache.misses++
+	cache.lock.Unlock()
+
+	statement, err = self.database.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	// Another query may have prepared the same statement in the meantime.
+	element, exists = cache.entries[query]
+	if exists {
+		_ = statement.Close()
+		statement, release = sqlStatementAcquire(self, element)
+		return statement, release, nil
+	}
+
+	element = cache.order.PushFront(&sqlStatementEntry{query: query, statement: statement})
+	cache.entries[query] = element
+	statement, release = sqlStatementAcquire(self, element)
+	sqlStatementCacheEvict(self)
+	return statement, release, nil
+}
+
+// sqlStatementCached finds the cached prepared statement of a query, if any.
+//
+// Release must be invoked once the statement is no longer used.
+func sqlStatementCached(self *Sql, query string) (statement *sql.Stmt, release func() error) {
+	cache := self.statements
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	element, exists := cache.entries[query]
+	if !exists {
+		return nil, nil
+	}
+
+	return sqlStatementAcquire(self, element)
+}
+
+// sqlIsConnectionError checks if an error is caused by a broken connection.
+func sqlIsConnectionError(err error) bool {
+	var netError net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netError)
+}
+
+// sqlStatementInvalidate removes a cached statement if err is caused by a broken connection,
+// so that the next query prepares it again.
+func sqlStatementInvalidate(self *Sql, query string, statement *sql.Stmt, err error) {
+	if nil == err || !sqlIsConnectionError(err) {
+		return
+	}
+
+	cache := self.statements
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	element, exists := cache.entries[query]
+	if !exists || statement != element.Value.(*sqlStatementEntry).statement {
+		return
+	}
+
+	sqlStatementRemove(self, element)
+}
@@ -0,0 +1,134 @@
+package frizzante
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSqlStatementCache(test *testing.T) {
+	sqlite := sqliteCreate(test)
+	SqlWithStatementCacheSize(sqlite, 2)
+
+	if _, createError := SqlTryExecute(sqlite, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"); createError != nil {
+		test.Fatal(createError)
+	}
+
+	for id := range 3 {
+		if _, insertError := SqlTryExecute(sqlite, "INSERT INTO users (id, name) VALUES (?, ?)", id, "test"); insertError != nil {
+			test.Fatal(insertError)
+		}
+	}
+
+	statistics := SqlStats(sqlite)
+	if 1 != statistics.MaxOpenConnections {
+		test.Fatalf("max open connections were expected to be 1, received %d instead", statistics.MaxOpenConnections)
+	}
+
+	if 0 != statistics.StatementCacheMisses || 0 != statistics.StatementCacheHits || 0 != statistics.StatementsCached {
+		test.Fatalf("statements of transactions were expected not to be prepared, received %d misses and %d hits instead", statistics.StatementCacheMisses, statistics.StatementCacheHits)
+	}
+
+	for range 2 {
+		names, findError := SqlFindAll[string](sqlite, "SELECT name FROM users WHERE id < ?", 2)
+		if findError != nil || 2 != len(names) {
+			test.Fatalf("2 names were expected, received %v instead", names)
+		}
+
+		rows, findError := SqlTryFind(sqlite, "SELECT COUNT(*) FROM users")
+		if findError != nil {
+			test.Fatal(findError)
+		}
+
+		var count int
+		if !SqlRowsNext(rows, &count) || 3 != count {
+			test.Fatalf("count was expected to be 3, received %d instead", count)
+		}
+		_ = SqlRowsClose(rows)
+	}
+
+	statistics = SqlStats(sqlite)
+	if 2 != statistics.StatementCacheMisses || 2 != statistics.StatementCacheHits {
+		test.Fatalf("find statements were expected to be reused, received %d misses and %d hits instead", statistics.StatementCacheMisses, statistics.StatementCacheHits)
+	}
+
+	if ids, findError := SqlFindAll[int](sqlite, "SELECT id FROM users"); findError != nil || 3 != len(ids) {
+		test.Fatalf("3 ids were expected, received %v instead", ids)
+	}
+
+	statistics = SqlStats(sqlite)
+	if 2 != statistics.StatementsCached {
+		test.Fatalf("cache was expected to hold 2 statements, received %d instead", statistics.StatementsCached)
+	}
+
+	if statement, _ := sqlStatementCached(sqlite, "SELECT name FROM users WHERE id < ?"); nil != statement {
+		test.Fatal("least recently used statement was expected to be evicted")
+	}
+
+	statement, release, prepareError := sqlPrepare(sqlite, context.Background(), "SELECT COUNT(*) FROM users")
+	if prepareError != nil {
+		test.Fatal(prepareError)
+	}
+
+	sqlStatementCacheClear(sqlite)
+
+	var count int
+	if scanError := statement.QueryRow().Scan(&count); scanError != nil || 3 != count {
+		test.Fatalf("statements in use were expected to survive the cache being cleared, received %d and error %v instead", count, scanError)
+	}
+
+	if releaseError := release(); releaseError != nil {
+		test.Fatal(releaseError)
+	}
+
+	if scanError := statement.QueryRow().Scan(&count); nil == scanError {
+		test.Fatal("statements removed from the cache were expected to be closed once released")
+	}
+
+	SqlWithStatementCacheSize(sqlite, 0)
+	if _, insertError := SqlTryExecute(sqlite, "INSERT INTO users (id, name) VALUES (?, ?)", 3, "test"); insertError != nil {
+		test.Fatal(insertError)
+	}
+
+	if cached := SqlStats(sqlite).StatementsCached; 0 != cached {
+		test.Fatalf("disabled cache was expected to hold no statements, received %d instead", cached)
+	}
+
+	server := ServerCreate()
+	ServerWithSql(server, sqlite)
+
+	var builder strings.Builder
+	writeError := MetricsWrite(server.metrics, &builder)
+	if writeError != nil {
+		test.Fatal(writeError)
+	}
+
+	metrics := builder.String()
+	for _, expected := range []string{"frizzante_sql_connections_max_open 1\n", "frizzante_sql_statement_cache_hits_total 3\n"} {
+		if !strings.Contains(metrics, expected) {
+			test.Fatalf("metrics were expected to contain `%s`, received `%s` instead", expected, metrics)
+		}
+	}
+
+	// Statements evicted while other queries are using them must not be closed under them.
+	SqlWithStatementCacheSize(sqlite, 1)
+	var group sync.WaitGroup
+	failures := make(chan error, 20)
+	for index := range 20 {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			_, findError := SqlFindAll[string](sqlite, fmt.Sprintf("SELECT name FROM users WHERE id < %d", index%4))
+			if findError != nil {
+				failures <- findError
+			}
+		}()
+	}
+	group.Wait()
+	close(failures)
+	for failure := range failures {
+		test.Fatalf("concurrent queries were expected to succeed while evicting statements, received %v instead", failure)
+	}
+}
@@ -0,0 +1,228 @@
+package frizzante
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+type SqlTx struct {
+	sql         *Sql
+	transaction *sql.Tx
+	ctx         context.Context
+	depth       int
+}
+
+// SqlTransaction runs fn within a transaction.
+//
+// The transaction is committed if fn returns nil,
+// otherwise it is rolled back and the error is returned.
+//
+// If fn panics, the transaction is rolled back and the panic is propagated.
+//
+// Use SqlTxTransaction to nest transactions.
+func SqlTransaction(self *Sql, fn func(tx *SqlTx) error) error {
+	return SqlTransactionWithContext(self, context.Background(), fn)
+}
+
+// SqlTransactionWithContext runs fn within a transaction.
+//
+// It works like SqlTransaction, except the transaction is rolled back as soon as ctx is done.
+func SqlTransactionWithContext(self *Sql, ctx context.Context, fn func(tx *SqlTx) error) (err error) {
+	return sqlTransaction(self, ctx, self.database.BeginTx, fn)
+}
+
+// sqlTransaction runs fn within a transaction started with begin,
+// like on the pool of the database or on a single connection.
+func sqlTransaction(
+	self *Sql,
+	ctx context.Context,
+	begin func(ctx context.Context, options *sql.TxOptions) (*sql.Tx, error),
+	fn func(tx *SqlTx) error,
+) (err error) {
+	ctx, span := SpanStart(ctx, "sql transaction")
+	defer SpanEnd(span)
+
+	transaction, beginError := begin(ctx, nil)
+	if beginError != nil {
+		SpanWithError(span, beginError)
+		return beginError
+	}
+
+	tx := &SqlTx{
+		sql:         self,
+		transaction: transaction,
+		ctx:         ctx,
+	}
+
+	defer func() {
+		recovered := recover()
+		if nil == recovered {
+			return
+		}
+
+		rollbackError := transaction.Rollback()
+		if rollbackError != nil {
+			NotifierSendError(notifierWithContext(self.notifier, ctx), rollbackError)
+		}
+		panic(recovered)
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		SpanWithError(span, err)
+		rollbackError := transaction.Rollback()
+		if rollbackError != nil {
+			return errors.Join(err, rollbackError)
+		}
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		SpanWithError(span, err)
+	}
+	return err
+}
+
+// SqlTxTransaction runs fn within a nested transaction, using a savepoint.
+//
+// The savepoint is released if fn returns nil,
+// otherwise only the changes made by fn are rolled back and the error is returned,
+// the outer transaction can still be committed.
+//
+// If fn panics, the changes made by fn are rolled back and the panic is propagated.
+func SqlTxTransaction(self *SqlTx, fn func(tx *SqlTx) error) (err error) {
+	savepoint := fmt.Sprintf("frizzante_savepoint_%d", self.depth+1)
+
+	_, err = SqlTxExecute(self, "SAVEPOINT "+savepoint)
+	if err != nil {
+		return err
+	}
+
+	tx := &SqlTx{
+		sql:         self.sql,
+		transaction: self.transaction,
+		ctx:         self.ctx,
+		depth:       self.depth + 1,
+	}
+
+	defer func() {
+		recovered := recover()
+		if nil == recovered {
+			return
+		}
+
+		_, rollbackError := SqlTxExecute(self, "ROLLBACK TO SAVEPOINT "+savepoint)
+		if rollbackError != nil {
+			NotifierSendError(notifierWithContext(self.sql.notifier, self.ctx), rollbackError)
+		}
+		panic(recovered)
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		_, rollbackError := SqlTxExecute(self, "ROLLBACK TO SAVEPOINT "+savepoint)
+		if rollbackError != nil {
+			return errors.Join(err, rollbackError)
+		}
+		return err
+	}
+
+	_, err = SqlTxExecute(self, "RELEASE SAVEPOINT "+savepoint)
+	return err
+}
+
+// SqlTxExecute executes sql queries that don't return rows within a transaction,
+// typically INSERT, UPDATE, DELETE queries.
+func SqlTxExecute(self *SqlTx, query string, props ...any) (sql.Result, error) {
+	ctx, span := sqlSpanStart(self.sql, self.ctx, "sql execute", query)
+	defer SpanEnd(span)
+
+	query, props, rewriteError := sqlQueryRewrite(self.sql.dialect, query, props)
+	if rewriteError != nil {
+		SpanWithError(span, rewriteError)
+		return nil, rewriteError
+	}
+
+	// Statements are only prepared outside of transactions,
+	// because preparing them requires a connection other than the one of the transaction.
+	statement, release := sqlStatementCached(self.sql, query)
+	if nil == statement {
+		result, execError := self.transaction.ExecContext(ctx, query, props...)
+		if execError != nil {
+			SpanWithError(span, execError)
+			return nil, execError
+		}
+		return result, nil
+	}
+	defer release()
+
+	transactionStatement := self.transaction.StmtContext(ctx, statement)
+	defer transactionStatement.Close()
+
+	result, execError := transactionStatement.ExecContext(ctx, props...)
+	if execError != nil {
+		SpanWithError(span, execError)
+		sqlStatementInvalidate(self.sql, query, statement, execError)
+		return nil, execError
+	}
+
+	return result, nil
+}
+
+// SqlTxFind executes a sql query that returns rows within a transaction, typically a SELECT query.
+//
+// It works like SqlTryFind, rows are iterated with SqlRowsNext,
+// which closes them as soon as there are no more rows or a row can't be projected, see SqlRowsError.
+//
+// Rows must be closed before executing other queries within the same transaction.
+func SqlTxFind(self *SqlTx, query string, props ...any) (*SqlRows, error) {
+	ctx, span := sqlSpanStart(self.sql, self.ctx, "sql find", query)
+
+	query, props, rewriteError := sqlQueryRewrite(self.sql.dialect, query, props)
+	if rewriteError != nil {
+		SpanWithError(span, rewriteError)
+		SpanEnd(span)
+		return nil, rewriteError
+	}
+
+	var rows *sql.Rows
+	var queryError error
+	var release func() error
+	statement, releaseCached := sqlStatementCached(self.sql, query)
+	if nil == statement {
+		rows, queryError = self.transaction.QueryContext(ctx, query, props...)
+	} else {
+		// Transaction statements are closed along with their rows,
+		// the cached statement is released only afterwards.
+		transactionStatement := self.transaction.StmtContext(ctx, statement)
+		release = func() error {
+			closeError := transactionStatement.Close()
+			releaseError := releaseCached()
+			if closeError != nil {
+				return closeError
+			}
+			return releaseError
+		}
+		rows, queryError = transactionStatement.QueryContext(ctx, props...)
+	}
+
+	if queryError != nil {
+		SpanWithError(span, queryError)
+		SpanEnd(span)
+		sqlStatementInvalidate(self.sql, query, statement, queryError)
+		if nil != release {
+			_ = release()
+		}
+		return nil, queryError
+	}
+
+	return &SqlRows{
+		sql:     self.sql,
+		release: release,
+		rows:    rows,
+		span:    span,
+	}, nil
+}
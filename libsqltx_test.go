@@ -0,0 +1,121 @@
+package frizzante
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSqlTransaction(test *testing.T) {
+	sqlite := sqliteCreate(test)
+	SqlExecute(sqlite, "CREATE TABLE fruits (name TEXT NOT NULL)")
+
+	count := func() (count int) {
+		next, close := SqlFind(sqlite, "SELECT COUNT(*) FROM fruits")
+		next(&count)
+		close()
+		return
+	}
+
+	transactionError := SqlTransaction(sqlite, func(tx *SqlTx) error {
+		_, insertError := SqlTxExecute(tx, "INSERT INTO fruits (name) VALUES (?)", "apples")
+		if insertError != nil {
+			return insertError
+		}
+		_, insertError = SqlTxExecute(tx, "INSERT INTO fruits (name) VALUES (?)", "pears")
+		return insertError
+	})
+	if transactionError != nil {
+		test.Fatal(transactionError)
+	}
+
+	if 2 != count() {
+		test.Fatalf("transaction was expected to commit 2 rows, found %d instead", count())
+	}
+
+	failure := errors.New("failure")
+	transactionError = SqlTransaction(sqlite, func(tx *SqlTx) error {
+		_, _ = SqlTxExecute(tx, "INSERT INTO fruits (name) VALUES (?)", "plums")
+		return failure
+	})
+	if !errors.Is(transactionError, failure) {
+		test.Fatalf("transaction was expected to return the error of fn, received %v instead", transactionError)
+	}
+
+	if 2 != count() {
+		test.Fatalf("failed transaction was expected to roll back, found %d rows instead", count())
+	}
+
+	func() {
+		defer func() {
+			if nil == recover() {
+				test.Fatal("panic was expected to be propagated")
+			}
+		}()
+		_ = SqlTransaction(sqlite, func(tx *SqlTx) error {
+			_, _ = SqlTxExecute(tx, "INSERT INTO fruits (name) VALUES (?)", "plums")
+			panic("failure")
+		})
+	}()
+
+	if 2 != count() {
+		test.Fatalf("panicking transaction was expected to roll back, found %d rows instead", count())
+	}
+
+	transactionError = SqlTransaction(sqlite, func(tx *SqlTx) error {
+		_, _ = SqlTxExecute(tx, "INSERT INTO fruits (name) VALUES (?)", "cherries")
+
+		nestedError := SqlTxTransaction(tx, func(tx *SqlTx) error {
+			_, _ = SqlTxExecute(tx, "INSERT INTO fruits (name) VALUES (?)", "plums")
+			return failure
+		})
+		if !errors.Is(nestedError, failure) {
+			test.Fatalf("nested transaction was expected to return the error of fn, received %v instead", nestedError)
+		}
+
+		return SqlTxTransaction(tx, func(tx *SqlTx) error {
+			_, insertError := SqlTxExecute(tx, "INSERT INTO fruits (name) VALUES (?)", "grapes")
+			return insertError
+		})
+	})
+	if transactionError != nil {
+		test.Fatal(transactionError)
+	}
+
+	var names []string
+	transactionError = SqlTransaction(sqlite, func(tx *SqlTx) error {
+		rows, findError := SqlTxFind(tx, "SELECT name FROM fruits ORDER BY rowid")
+		if findError != nil {
+			return findError
+		}
+		defer SqlRowsClose(rows)
+
+		var name string
+		for SqlRowsNext(rows, &name) {
+			names = append(names, name)
+		}
+		return SqlRowsError(rows)
+	})
+	if transactionError != nil {
+		test.Fatal(transactionError)
+	}
+
+	transactionError = SqlTransaction(sqlite, func(tx *SqlTx) error {
+		rows, findError := SqlTxFind(tx, "SELECT name FROM fruits ORDER BY rowid")
+		if findError != nil {
+			return findError
+		}
+		defer SqlRowsClose(rows)
+
+		var count int
+		for SqlRowsNext(rows, &count) {
+		}
+		return SqlRowsError(rows)
+	})
+	if nil == transactionError {
+		test.Fatal("transaction was expected to return the error of rows that can't be projected")
+	}
+
+	if 4 != len(names) || "cherries" != names[2] || "grapes" != names[3] {
+		test.Fatalf("nested transactions were expected to roll back only their own changes, found %v instead", names)
+	}
+}
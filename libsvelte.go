@@ -1,6 +1,7 @@
 package frizzante
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"github.com/evanw/esbuild/pkg/api"
@@ -18,27 +19,32 @@ const (
 	RenderHeadless Render = 3 // Renders only on the server and omits the base template.
 )
 
-func render(efs embed.FS, stringProps string) (string, string, error) {
+// renderBundle loads the server side render bundle and converts it to common js.
+func renderBundle(efs embed.FS) (string, error) {
 	renderFileName := filepath.Join(".dist", "server", "render.server.js")
 
 	var renderEsmBytes []byte
 	if "1" == os.Getenv("DEV") {
 		renderEsmBytesLocal, readError := os.ReadFile(renderFileName)
 		if readError != nil {
-			return "", "", readError
+			return "", readError
 		}
 		renderEsmBytes = renderEsmBytesLocal
 	} else {
 		renderEsmBytesLocal, readError := efs.ReadFile(renderFileName)
 		if readError != nil {
-			return "", "", readError
+			return "", readError
 		}
 		renderEsmBytes = renderEsmBytesLocal
 	}
 
 	renderEsm := string(renderEsmBytes)
 
-	renderCjs, javaScriptBundleError := JavaScriptBundle(".", api.FormatCommonJS, renderEsm)
+	return JavaScriptBundle(".", api.FormatCommonJS, renderEsm)
+}
+
+func render(ctx context.Context, efs embed.FS, stringProps string) (string, string, error) {
+	renderCjs, javaScriptBundleError := renderBundle(efs)
 	if javaScriptBundleError != nil {
 		return "", "", javaScriptBundleError
 	}
@@ -89,7 +95,7 @@ func render(efs embed.FS, stringProps string) (string, string, error) {
 		},
 	}
 
-	_, destroy, javaScriptError := JavaScriptRun(doneCjs, globals)
+	_, destroy, javaScriptError := JavaScriptRunWithContext(ctx, doneCjs, globals)
 	if javaScriptError != nil {
 		return head, body, javaScriptError
 	}
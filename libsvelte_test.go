@@ -30,7 +30,7 @@ func TestRenderServer(test *testing.T) {
 	time.Sleep(1 * time.Second)
 
 	expected := "<h1>Hello world.</h1>"
-	actual, getError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/welcome", port), nil)
+	actual, getError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/", port), nil)
 	if getError != nil {
 		test.Fatal(getError)
 	}
@@ -0,0 +1,368 @@
+package frizzante
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SpanKind int64
+
+const (
+	SpanKindInternal SpanKind = 1 // An operation within the application.
+	SpanKindServer   SpanKind = 2 // An incoming http request.
+	SpanKindClient   SpanKind = 3 // An outgoing http request.
+)
+
+type Span struct {
+	TraceId      string
+	SpanId       string
+	ParentSpanId string
+	Name         string
+	Kind         SpanKind
+	StartedAt    time.Time
+	EndedAt      time.Time
+	Attributes   map[string]any
+	Error        string
+	Sampled      bool
+	tracer       *Tracer
+	lock         sync.Mutex
+	ended        bool
+}
+
+// SpanExporter receives spans as soon as they end.
+type SpanExporter = func(span *Span)
+
+type Tracer struct {
+	serviceName string
+	exporter    SpanExporter
+}
+
+type spanContextKey struct{}
+
+// TracerCreate creates a tracer.
+//
+// Spans are discarded until an exporter is set.
+func TracerCreate() *Tracer {
+	return &Tracer{
+		serviceName: "frizzante",
+		exporter:    func(span *Span) {},
+	}
+}
+
+// TracerWithServiceName sets the name of the service spans are attributed to.
+func TracerWithServiceName(self *Tracer, serviceName string) {
+	self.serviceName = serviceName
+}
+
+// TracerWithExporter sets the exporter of the tracer.
+func TracerWithExporter(self *Tracer, exporter SpanExporter) {
+	self.exporter = exporter
+}
+
+// ServerWithTracer sets the tracer of the server.
+//
+// Each request is traced with a server span, guards, handlers,
+// page compilations, sql statements and outgoing http requests are traced with child spans.
+func ServerWithTracer(self *Server, tracer *Tracer) {
+	self.tracer = tracer
+}
+
+// traceId creates a random id of the given size, encoded as hex.
+func traceId(size int) string {
+	id := make([]byte, size)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// traceParentParse parses a W3C traceparent header into its trace id, parent span id and sampled flag.
+func traceParentParse(traceParent string) (traceId string, parentSpanId string, sampled bool, ok bool) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || 2 != len(parts[0]) || 32 != len(parts[1]) || 16 != len(parts[2]) || 2 != len(parts[3]) {
+		return
+	}
+
+	if "ff" == parts[0] || ("00" == parts[0] && 4 != len(parts)) {
+		return
+	}
+
+	for _, part := range parts[:4] {
+		if _, decodeError := hex.DecodeString(part); decodeError != nil || strings.ToLower(part) != part {
+			return
+		}
+	}
+
+	if strings.Repeat("0", 32) == parts[1] || strings.Repeat("0", 16) == parts[2] {
+		return
+	}
+
+	flags, _ := strconv.ParseUint(parts[3], 16, 8)
+	return parts[1], parts[2], 1 == flags&1, true
+}
+
+// SpanTraceParent formats the span as a W3C traceparent header.
+func SpanTraceParent(self *Span) string {
+	if nil == self {
+		return ""
+	}
+
+	flags := "00"
+	if self.Sampled {
+		flags = "01"
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", self.TraceId, self.SpanId, flags)
+}
+
+// tracerSpanStart starts a span with the given tracer.
+//
+// The span is a child of the span found in ctx, or else of the remote span described by traceParent.
+// When neither exists, the span starts a new trace.
+func tracerSpanStart(self *Tracer, ctx context.Context, name string, kind SpanKind, traceParent string) (context.Context, *Span) {
+	span := &Span{
+		SpanId:     traceId(8),
+		Name:       name,
+		Kind:       kind,
+		StartedAt:  time.Now(),
+		Attributes: map[string]any{},
+		Sampled:    true,
+		tracer:     self,
+	}
+
+	parent := SpanFromContext(ctx)
+	if nil != parent {
+		span.TraceId = parent.TraceId
+		span.ParentSpanId = parent.SpanId
+		span.Sampled = parent.Sampled
+	} else if remoteTraceId, remoteSpanId, sampled, ok := traceParentParse(traceParent); ok {
+		span.TraceId = remoteTraceId
+		span.ParentSpanId = remoteSpanId
+		span.Sampled = sampled
+	} else {
+		span.TraceId = traceId(16)
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// spanStart starts a child of the span found in ctx.
+//
+// When ctx carries no span, tracing is disabled and spanStart returns ctx and a nil span.
+func spanStart(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if nil == parent {
+		return ctx, nil
+	}
+
+	return tracerSpanStart(parent.tracer, ctx, name, kind, "")
+}
+
+// SpanFromContext finds the span carried by ctx, or nil if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SpanStart starts a child of the span found in ctx, like the span of a request.
+//
+// When ctx carries no span, tracing is disabled and SpanStart returns ctx and a nil span,
+// all span functions are safe to invoke with a nil span.
+func SpanStart(ctx context.Context, name string) (context.Context, *Span) {
+	return spanStart(ctx, name, SpanKindInternal)
+}
+
+// SpanWithAttribute sets an attribute of the span.
+func SpanWithAttribute(self *Span, key string, value any) {
+	if nil == self {
+		return
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.Attributes[key] = value
+}
+
+// SpanWithError marks the span as failed.
+func SpanWithError(self *Span, err error) {
+	if nil == self || nil == err {
+		return
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.Error = err.Error()
+}
+
+// SpanEnd ends the span and sends it to the exporter of its tracer.
+//
+// Spans that are not sampled are not exported.
+func SpanEnd(self *Span) {
+	if nil == self {
+		return
+	}
+
+	self.lock.Lock()
+	if self.ended {
+		self.lock.Unlock()
+		return
+	}
+	self.ended = true
+	self.EndedAt = time.Now()
+	self.lock.Unlock()
+
+	if self.Sampled {
+		self.tracer.exporter(self)
+	}
+}
+
+// requestSpan invokes function within a child span of the request.
+//
+// While function runs, the request context carries the child span,
+// so that spans started from the request context are nested under it.
+func requestSpan(self *Request, name string, function func()) {
+	ctx, span := SpanStart(self.httpRequest.Context(), name)
+	if nil == span {
+		function()
+		return
+	}
+
+	httpRequest := self.httpRequest
+	self.httpRequest = httpRequest.WithContext(ctx)
+	defer func() {
+		self.httpRequest = httpRequest
+		SpanEnd(span)
+	}()
+
+	function()
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpValueCreate(value any) otlpValue {
+	switch typed := value.(type) {
+	case string:
+		return otlpValue{StringValue: &typed}
+	case bool:
+		return otlpValue{BoolValue: &typed}
+	case int:
+		formatted := strconv.FormatInt(int64(typed), 10)
+		return otlpValue{IntValue: &formatted}
+	case int64:
+		formatted := strconv.FormatInt(typed, 10)
+		return otlpValue{IntValue: &formatted}
+	case float64:
+		return otlpValue{DoubleValue: &typed}
+	default:
+		formatted := fmt.Sprint(typed)
+		return otlpValue{StringValue: &formatted}
+	}
+}
+
+// otlpTracesCreate converts a span into an OTLP/JSON export request.
+func otlpTracesCreate(span *Span) otlpTraces {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+
+	var attributes []otlpAttribute
+	for key, value := range span.Attributes {
+		attributes = append(attributes, otlpAttribute{Key: key, Value: otlpValueCreate(value)})
+	}
+
+	status := otlpStatus{Code: 1}
+	if "" != span.Error {
+		status = otlpStatus{Code: 2, Message: span.Error}
+	}
+
+	scopeSpans := otlpScopeSpans{Spans: []otlpSpan{{
+		TraceId:           span.TraceId,
+		SpanId:            span.SpanId,
+		ParentSpanId:      span.ParentSpanId,
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.StartedAt.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndedAt.UnixNano(), 10),
+		Attributes:        attributes,
+		Status:            status,
+	}}}
+	scopeSpans.Scope.Name = "frizzante"
+
+	resourceSpans := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scopeSpans}}
+	resourceSpans.Resource.Attributes = []otlpAttribute{{
+		Key:   "service.name",
+		Value: otlpValueCreate(span.tracer.serviceName),
+	}}
+
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{resourceSpans}}
+}
+
+// OtlpFileExporterCreate creates an exporter that writes spans to a file using the OTLP/JSON format,
+// one export request per line, which is the format read by the file receiver of the OpenTelemetry Collector.
+func OtlpFileExporterCreate(file *os.File, notifier *Notifier) SpanExporter {
+	var lock sync.Mutex
+	return func(span *Span) {
+		traces, marshalError := json.Marshal(otlpTracesCreate(span))
+		if marshalError != nil {
+			NotifierSendError(notifier, marshalError)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		_, writeError := file.Write(append(traces, '\n'))
+		if writeError != nil {
+			NotifierSendError(notifier, writeError)
+		}
+	}
+}
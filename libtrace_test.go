@@ -0,0 +1,184 @@
+package frizzante
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTraceParentParse(test *testing.T) {
+	traceId, parentSpanId, sampled, ok := traceParentParse("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		test.Fatal("traceparent was expected to be valid")
+	}
+	if "4bf92f3577b34da6a3ce929d0e0e4736" != traceId || "00f067aa0ba902b7" != parentSpanId || !sampled {
+		test.Fatalf("unexpected traceparent trace id '%s', parent span id '%s' and sampled '%t'", traceId, parentSpanId, sampled)
+	}
+
+	invalid := []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	}
+	for _, traceParent := range invalid {
+		if _, _, _, ok := traceParentParse(traceParent); ok {
+			test.Fatalf("traceparent '%s' was expected to be invalid", traceParent)
+		}
+	}
+}
+
+func TestServerWithTracer(test *testing.T) {
+	var lock sync.Mutex
+	var spans []*Span
+
+	tracer := TracerCreate()
+	TracerWithExporter(tracer, func(span *Span) {
+		lock.Lock()
+		defer lock.Unlock()
+		spans = append(spans, span)
+	})
+
+	server := ServerCreate()
+	port := NextNumber(8080)
+	ServerWithPort(server, port)
+	ServerWithNotifier(server, NotifierCreate())
+	ServerWithTracer(server, tracer)
+	ServerWithApiGuard(server, func(req *Request, res *Response, pass func()) {
+		pass()
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /outer")
+		serve(func(request *Request, response *Response) {
+			inner, innerError := HttpGetWithContext(ReceiveContext(request), fmt.Sprintf("http://127.0.0.1:%d/inner", port), nil)
+			if innerError != nil {
+				test.Error(innerError)
+			}
+			SendEcho(response, inner)
+		})
+	})
+	ServerWithApi(server, func(
+		route func(pattern string),
+		serve func(serveFunction func(req *Request, res *Response)),
+	) {
+		route("GET /inner")
+		serve(func(_ *Request, response *Response) {
+			SendEcho(response, "inner")
+		})
+	})
+	go ServerStart(server)
+	defer ServerStop(server)
+
+	time.Sleep(1 * time.Second)
+
+	_, getError := HttpGet(fmt.Sprintf("http://127.0.0.1:%d/outer", port), map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	if getError != nil {
+		test.Fatal(getError)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	named := map[string]*Span{}
+	for _, span := range spans {
+		if "4bf92f3577b34da6a3ce929d0e0e4736" != span.TraceId {
+			test.Fatalf("span '%s' was expected to belong to the inbound trace, received trace id '%s' instead", span.Name, span.TraceId)
+		}
+		named[span.Name] = span
+	}
+
+	outer := named["GET /outer"]
+	inner := named["GET /inner"]
+	client := named["HTTP GET"]
+	if nil == outer || nil == inner || nil == client {
+		test.Fatalf("spans for both requests and for the outgoing request were expected, received %d spans instead", len(spans))
+	}
+
+	if "00f067aa0ba902b7" != outer.ParentSpanId || SpanKindServer != outer.Kind {
+		test.Fatalf("outer span was expected to be a server span child of the inbound span, received parent '%s' instead", outer.ParentSpanId)
+	}
+
+	var guard *Span
+	var handler *Span
+	for _, span := range spans {
+		if outer.SpanId != span.ParentSpanId {
+			continue
+		}
+		if strings.HasPrefix(span.Name, "guard ") {
+			guard = span
+		}
+		if strings.HasPrefix(span.Name, "handler ") {
+			handler = span
+		}
+	}
+
+	if nil == guard || nil == handler {
+		test.Fatal("guard and handler spans were expected to be children of the outer span")
+	}
+
+	if handler.SpanId != client.ParentSpanId || SpanKindClient != client.Kind {
+		test.Fatal("outgoing request span was expected to be a client span child of the handler span")
+	}
+
+	if client.SpanId != inner.ParentSpanId {
+		test.Fatal("inner span was expected to be a child of the outgoing request span")
+	}
+
+	if 200 != inner.Attributes["http.response.status_code"] {
+		test.Fatalf("inner span was expected to have status code 200, received '%v' instead", inner.Attributes["http.response.status_code"])
+	}
+}
+
+func TestOtlpFileExporterCreate(test *testing.T) {
+	fileName := filepath.Join(test.TempDir(), "traces.jsonl")
+	file, createError := os.Create(fileName)
+	if createError != nil {
+		test.Fatal(createError)
+	}
+	defer file.Close()
+
+	tracer := TracerCreate()
+	TracerWithServiceName(tracer, "shop")
+	TracerWithExporter(tracer, OtlpFileExporterCreate(file, NotifierCreate()))
+
+	ctx, root := tracerSpanStart(tracer, context.Background(), "root", SpanKindServer, "")
+	_, child := SpanStart(ctx, "child")
+	SpanWithAttribute(child, "db.statement", "select 1")
+	SpanWithError(child, fmt.Errorf("failed"))
+	SpanEnd(child)
+	SpanEnd(root)
+
+	contents, readError := os.ReadFile(fileName)
+	if readError != nil {
+		test.Fatal(readError)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	if 2 != len(lines) {
+		test.Fatalf("exporter was expected to write 2 lines, received %d instead", len(lines))
+	}
+
+	expected := []string{
+		`"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"shop"}}]}`,
+		fmt.Sprintf(`"traceId":"%s","spanId":"%s","parentSpanId":"%s","name":"child","kind":1`, root.TraceId, child.SpanId, root.SpanId),
+		`{"key":"db.statement","value":{"stringValue":"select 1"}}`,
+		`"status":{"code":2,"message":"failed"}`,
+	}
+	for _, part := range expected {
+		if !strings.Contains(lines[0], part) {
+			test.Fatalf("exported span was expected to contain '%s', received '%s' instead", part, lines[0])
+		}
+	}
+}
@@ -4,6 +4,7 @@
  * @property {function(string,Record<string,string>,false|Record<string,any>)} navigate
  * @property {string} query
  * @property {Record<string,any>} data
+ * @property {{token:string}} csrf
  */
 
 /**
@@ -16,12 +17,16 @@ function done(payload) {
         navigate,
         query,
         data,
+        csrf,
     } = payload
 
     /**
      * @param {Response} response
      */
     return function (response) {
+        // The token is renewed along with the session, like after logging in.
+        csrf.token = response.headers.get("X-Csrf-Token") ?? csrf.token
+
         if (response.status >= 300) {
             console.error(`Submit request failed with status ${response.status} ${response.statusText}.`)
             return
@@ -64,13 +69,14 @@ function fail(reason) {
  * @property {function(string):{page:string,parameters:Record<string,string>}} page
  * @property {function(string,Record<string,string>,false|Record<string,any>)} navigate
  * @property {Record<string,any>} data
+ * @property {{token:string}} csrf
  */
 
 /**
  * @param {UpdatePayload} payload
  */
 export function update(payload) {
-    const {page, navigate, data} = payload
+    const {page, navigate, data, csrf} = payload
     return function onsubmit(e) {
         e.preventDefault()
         /** @type {HTMLFormElement} */
@@ -93,6 +99,7 @@ export function update(payload) {
                 navigate,
                 query,
                 data,
+                csrf,
             }
 
             fetch(query, init).then(done(donePayload)).catch(fail)
@@ -105,6 +112,7 @@ export function update(payload) {
             navigate,
             query: "",
             data,
+            csrf,
         }
 
         fetch(formElement.action, init).then(done(donePayload)).catch(fail)